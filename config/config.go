@@ -0,0 +1,354 @@
+package config
+
+import (
+	"bot-main/models"
+	"bot-main/selection"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of the bot YAML configuration file.
+// Zero values mean "not set", defaults and command line flags are used then.
+type Config struct {
+	Account AccountConfig `yaml:"account"`
+	// Several accounts watched concurrently, used instead of account.
+	Accounts      []AccountConfig      `yaml:"accounts"`
+	Proceeding    ProceedingConfig     `yaml:"proceeding"`
+	Queue         QueueConfig          `yaml:"queue"`
+	Preferences   PreferencesConfig    `yaml:"preferences"`
+	Strategy      StrategyConfig       `yaml:"strategy"`
+	Reservation   ReservationConfig    `yaml:"reservation"`
+	Polling       PollingConfig        `yaml:"polling"`
+	Notifications []NotificationConfig `yaml:"notifications"`
+	HTTP          HTTPConfig           `yaml:"http"`
+}
+
+type AccountConfig struct {
+	Email    string `yaml:"email"`
+	Password string `yaml:"password"`
+	// Secret file with the password, preferred over keeping it in this file.
+	PasswordFile string `yaml:"password_file"`
+	// Alias of the account in the vault, used instead of the values above.
+	Alias     string `yaml:"alias"`
+	VaultFile string `yaml:"vault_file"`
+}
+
+// ProceedingConfig selects watched proceedings, only one way may be used.
+type ProceedingConfig struct {
+	Index     *int   `yaml:"index"`
+	ID        string `yaml:"id"`
+	Signature string `yaml:"signature"`
+	// Type ID or its English or Polish name.
+	Type string `yaml:"type"`
+	// Every active proceeding appointments can be made for.
+	All bool `yaml:"all"`
+}
+
+// Selector returns the proceeding selector in the form of -proceeding flag,
+// empty when proceedings are selected by index.
+func (p ProceedingConfig) Selector() string {
+	switch {
+	case p.All:
+		return "all"
+	case p.ID != "":
+		return "id:" + p.ID
+	case p.Signature != "":
+		return "signature:" + p.Signature
+	case p.Type != "":
+		return "type:" + p.Type
+	}
+	return ""
+}
+
+type QueueConfig struct {
+	Index *int `yaml:"index"`
+	// Queues watched in order of preference, matched by localization,
+	// prefix, ID or any translated name. Used instead of index.
+	Preferred []string `yaml:"preferred"`
+}
+
+type PreferencesConfig struct {
+	// Dates as 2006-01-02.
+	EarliestDate string `yaml:"earliest_date"`
+	LatestDate   string `yaml:"latest_date"`
+	// English weekday names or their abbreviations, e.g. [mon, tue].
+	Weekdays      []string `yaml:"weekdays"`
+	BlackoutDates []string `yaml:"blackout_dates"`
+	// Polish public holidays are skipped unless this is false.
+	SkipPublicHolidays *bool `yaml:"skip_public_holidays"`
+	// Times of day as 15:04, in Warsaw time.
+	EarliestTime string `yaml:"earliest_time"`
+	LatestTime   string `yaml:"latest_time"`
+	// Windows slots must start in, like "Mon-Fri 09:00-12:00".
+	TimeWindows []string `yaml:"time_windows"`
+}
+
+// StrategyConfig chooses how free slots are ranked before booking.
+type StrategyConfig struct {
+	// One of "earliest", "latest", "nearest" or "weighted".
+	Name string `yaml:"name"`
+	// Date as 2006-01-02 the nearest and weighted strategies aim for.
+	TargetDate string `yaml:"target_date"`
+	// Time of day as 15:04 the weighted strategy aims for.
+	PreferredTime string        `yaml:"preferred_time"`
+	Weights       WeightsConfig `yaml:"weights"`
+}
+
+// WeightsConfig are weighted strategy penalties per day from the target date,
+// per hour from the preferred time and per position in queue preferences.
+type WeightsConfig struct {
+	Date  float64 `yaml:"date"`
+	Hour  float64 `yaml:"hour"`
+	Queue float64 `yaml:"queue"`
+}
+
+// SlotStrategy converts the section to the model.
+func (s StrategyConfig) SlotStrategy() models.SlotStrategy {
+	return models.SlotStrategy{
+		Name:          s.Name,
+		TargetDate:    s.TargetDate,
+		PreferredTime: s.PreferredTime,
+		DateWeight:    s.Weights.Date,
+		HourWeight:    s.Weights.Hour,
+		QueueWeight:   s.Weights.Queue,
+	}
+}
+
+type ReservationConfig struct {
+	// How many slots are tried in turn when they are already taken.
+	MaxAttempts *int `yaml:"max_attempts"`
+	// Book proceedings which already have an appointment.
+	AllowDoubleBooking bool             `yaml:"allow_double_booking"`
+	Reschedule         RescheduleConfig `yaml:"reschedule"`
+	// Print the reservation request instead of sending it.
+	DryRun bool `yaml:"dry_run"`
+}
+
+// RescheduleConfig makes the bot look only for slots earlier than
+// the appointment the proceeding already has.
+type RescheduleConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// How much earlier than the current appointment a slot must start.
+	Margin *time.Duration `yaml:"margin"`
+	// As 2006-01-02 15:04 in Warsaw time, the date of the AppointmentMade
+	// event is used when empty.
+	CurrentAppointment string `yaml:"current_appointment"`
+}
+
+type PollingConfig struct {
+	Interval    time.Duration `yaml:"interval"`
+	MaxRelogins *int          `yaml:"max_relogins"`
+	// How many date slots are looked up at once.
+	LookupConcurrency    *int          `yaml:"lookup_concurrency"`
+	ForbiddenRetryBudget time.Duration `yaml:"forbidden_retry_budget"`
+}
+
+type NotificationConfig struct {
+	// One of "console", "webhook" or "file".
+	Type string `yaml:"type"`
+	// Webhook URL the events are posted to.
+	Url string `yaml:"url"`
+	// File the events are appended to.
+	Path string `yaml:"path"`
+}
+
+type HTTPConfig struct {
+	BaseUrl            string                     `yaml:"base_url"`
+	Timeout            time.Duration              `yaml:"timeout"`
+	DailyRequestBudget *int                       `yaml:"daily_request_budget"`
+	BudgetFile         string                     `yaml:"budget_file"`
+	RateLimits         map[string]RateLimitConfig `yaml:"rate_limits"`
+}
+
+type RateLimitConfig struct {
+	PerMinute float64 `yaml:"per_minute"`
+	Burst     int     `yaml:"burst"`
+}
+
+var rateLimitFamilies = map[string]bool{
+	"login":       true,
+	"proceedings": true,
+	"dates":       true,
+	"slots":       true,
+	"reserve":     true,
+}
+
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Config error reading %s: %w", path, err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates the configuration, unknown keys are errors
+// so typos don't silently fall back to defaults.
+func Parse(data []byte) (*Config, error) {
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("Config YAML parcing error: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Config) Validate() error {
+	if len(c.Accounts) > 0 && c.Account != (AccountConfig{}) {
+		return fmt.Errorf("Config error: account and accounts can't be used together")
+	}
+	names := make(map[string]bool)
+	for i, account := range c.Accounts {
+		name := account.Alias
+		if name == "" {
+			name = account.Email
+		}
+		if name == "" {
+			return fmt.Errorf("Config error: accounts[%d] needs an alias or an email", i)
+		}
+		if names[name] {
+			return fmt.Errorf("Config error: accounts[%d] %s is listed twice", i, name)
+		}
+		names[name] = true
+	}
+
+	if c.Proceeding.Index != nil && *c.Proceeding.Index < 0 {
+		return fmt.Errorf("Config error: proceeding.index must not be negative")
+	}
+	selectors := 0
+	for _, set := range []bool{c.Proceeding.Index != nil, c.Proceeding.ID != "", c.Proceeding.Signature != "", c.Proceeding.Type != "", c.Proceeding.All} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors > 1 {
+		return fmt.Errorf("Config error: only one of proceeding index, id, signature, type and all may be set")
+	}
+	if c.Queue.Index != nil && len(c.Queue.Preferred) > 0 {
+		return fmt.Errorf("Config error: only one of queue index and preferred may be set")
+	}
+	for i, preferred := range c.Queue.Preferred {
+		if strings.TrimSpace(preferred) == "" {
+			return fmt.Errorf("Config error: queue.preferred[%d] is empty", i)
+		}
+	}
+	if c.Queue.Index != nil && *c.Queue.Index < 0 {
+		return fmt.Errorf("Config error: queue.index must not be negative")
+	}
+
+	for key, value := range map[string]string{
+		"preferences.earliest_date": c.Preferences.EarliestDate,
+		"preferences.latest_date":   c.Preferences.LatestDate,
+	} {
+		if _, err := parseOptional(time.DateOnly, value); err != nil {
+			return fmt.Errorf("Config error: %s %q is not a YYYY-MM-DD date", key, value)
+		}
+	}
+	for i, value := range c.Preferences.BlackoutDates {
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return fmt.Errorf("Config error: preferences.blackout_dates[%d] %q is not a YYYY-MM-DD date", i, value)
+		}
+	}
+	for i, value := range c.Preferences.TimeWindows {
+		if _, err := selection.ParseTimeWindow(value); err != nil {
+			return fmt.Errorf("Config error: preferences.time_windows[%d]: %w", i, err)
+		}
+	}
+	for i, value := range c.Preferences.Weekdays {
+		if _, err := selection.ParseWeekday(value); err != nil {
+			return fmt.Errorf("Config error: preferences.weekdays[%d]: %w", i, err)
+		}
+	}
+	for key, value := range map[string]string{
+		"preferences.earliest_time": c.Preferences.EarliestTime,
+		"preferences.latest_time":   c.Preferences.LatestTime,
+	} {
+		if _, err := parseOptional("15:04", value); err != nil {
+			return fmt.Errorf("Config error: %s %q is not a HH:MM time", key, value)
+		}
+	}
+	if c.Preferences.EarliestDate != "" && c.Preferences.LatestDate != "" && c.Preferences.EarliestDate > c.Preferences.LatestDate {
+		return fmt.Errorf("Config error: preferences.earliest_date is after preferences.latest_date")
+	}
+	if c.Preferences.EarliestTime != "" && c.Preferences.LatestTime != "" && c.Preferences.EarliestTime > c.Preferences.LatestTime {
+		return fmt.Errorf("Config error: preferences.earliest_time is after preferences.latest_time")
+	}
+
+	if _, err := selection.NewSlotSelector(c.Strategy.SlotStrategy()); err != nil {
+		return fmt.Errorf("Config error: strategy: %w", err)
+	}
+
+	if c.Reservation.MaxAttempts != nil && *c.Reservation.MaxAttempts < 1 {
+		return fmt.Errorf("Config error: reservation.max_attempts must be at least 1")
+	}
+
+	if c.Reservation.Reschedule.Margin != nil && *c.Reservation.Reschedule.Margin < 0 {
+		return fmt.Errorf("Config error: reservation.reschedule.margin must not be negative")
+	}
+	if value := c.Reservation.Reschedule.CurrentAppointment; value != "" {
+		if _, err := selection.ParseAppointmentTime(value); err != nil {
+			return fmt.Errorf("Config error: reservation.reschedule.current_appointment: %w", err)
+		}
+	}
+
+	if c.Polling.Interval < 0 || c.Polling.ForbiddenRetryBudget < 0 {
+		return fmt.Errorf("Config error: polling durations must not be negative")
+	}
+	if c.Polling.MaxRelogins != nil && *c.Polling.MaxRelogins < 0 {
+		return fmt.Errorf("Config error: polling.max_relogins must not be negative")
+	}
+	if c.Polling.LookupConcurrency != nil && *c.Polling.LookupConcurrency < 1 {
+		return fmt.Errorf("Config error: polling.lookup_concurrency must be at least 1")
+	}
+
+	for i, notification := range c.Notifications {
+		switch notification.Type {
+		case "console":
+		case "webhook":
+			if parsed, err := url.Parse(notification.Url); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+				return fmt.Errorf("Config error: notifications[%d] webhook needs a valid url", i)
+			}
+		case "file":
+			if notification.Path == "" {
+				return fmt.Errorf("Config error: notifications[%d] file needs a path", i)
+			}
+		default:
+			return fmt.Errorf("Config error: notifications[%d] has unknown type %q", i, notification.Type)
+		}
+	}
+
+	if c.HTTP.BaseUrl != "" {
+		if parsed, err := url.Parse(c.HTTP.BaseUrl); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("Config error: http.base_url %q is not a valid URL", c.HTTP.BaseUrl)
+		}
+	}
+	if c.HTTP.Timeout < 0 {
+		return fmt.Errorf("Config error: http.timeout must not be negative")
+	}
+	for family, limit := range c.HTTP.RateLimits {
+		if !rateLimitFamilies[family] {
+			return fmt.Errorf("Config error: http.rate_limits has unknown endpoint family %q", family)
+		}
+		if limit.PerMinute < 0 || limit.Burst < 0 {
+			return fmt.Errorf("Config error: http.rate_limits.%s must not be negative", family)
+		}
+	}
+
+	return nil
+}
+
+func parseOptional(layout, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(layout, value)
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`
+account:
+  email: user@example.com
+proceeding:
+  index: 0
+queue:
+  index: 1
+preferences:
+  earliest_date: 2025-10-01
+  latest_date: 2025-10-31
+  earliest_time: "09:00"
+  latest_time: "12:00"
+polling:
+  interval: 45s
+  max_relogins: 5
+notifications:
+  - type: console
+  - type: webhook
+    url: https://hooks.example.com/bot
+  - type: file
+    path: events.jsonl
+http:
+  timeout: 30s
+  daily_request_budget: 800
+  rate_limits:
+    slots:
+      per_minute: 20
+      burst: 2
+`)
+	cfg, err := Parse(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "user@example.com", cfg.Account.Email)
+	assert.Equal(t, 0, *cfg.Proceeding.Index)
+	assert.Equal(t, 1, *cfg.Queue.Index)
+	assert.Equal(t, "2025-10-01", cfg.Preferences.EarliestDate)
+	assert.Equal(t, "12:00", cfg.Preferences.LatestTime)
+	assert.Equal(t, 45*time.Second, cfg.Polling.Interval)
+	assert.Equal(t, 5, *cfg.Polling.MaxRelogins)
+	assert.Len(t, cfg.Notifications, 3)
+	assert.Equal(t, 30*time.Second, cfg.HTTP.Timeout)
+	assert.Equal(t, 800, *cfg.HTTP.DailyRequestBudget)
+	assert.Equal(t, RateLimitConfig{PerMinute: 20, Burst: 2}, cfg.HTTP.RateLimits["slots"])
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{name: "Account and accounts", data: "account:\n  email: a@example.com\naccounts:\n  - alias: anna\n"},
+		{name: "Account without alias and email", data: "accounts:\n  - password: secret\n"},
+		{name: "Account listed twice", data: "accounts:\n  - alias: anna\n  - alias: anna\n"},
+		{name: "Two proceeding selectors", data: "proceeding:\n  index: 0\n  all: true\n"},
+		{name: "Queue index and preferred", data: "queue:\n  index: 0\n  preferred: [Okopowa 7]\n"},
+		{name: "Empty preferred queue", data: "queue:\n  preferred: [\"\"]\n"},
+		{name: "Invalid weekday", data: "preferences:\n  weekdays: [mon, someday]\n"},
+		{name: "Invalid blackout date", data: "preferences:\n  blackout_dates: [2025-13-01]\n"},
+		{name: "Invalid time window", data: "preferences:\n  time_windows: [\"Mon-Fri 12:00-09:00\"]\n"},
+		{name: "Unknown strategy", data: "strategy:\n  name: random\n"},
+		{name: "Nearest strategy without target", data: "strategy:\n  name: nearest\n"},
+		{name: "Negative strategy weight", data: "strategy:\n  name: weighted\n  weights:\n    queue: -1\n"},
+		{name: "Zero lookup concurrency", data: "polling:\n  lookup_concurrency: 0\n"},
+		{name: "Zero reservation attempts", data: "reservation:\n  max_attempts: 0\n"},
+		{name: "Negative reschedule margin", data: "reservation:\n  reschedule:\n    margin: -1h\n"},
+		{name: "Invalid current appointment", data: "reservation:\n  reschedule:\n    current_appointment: tomorrow\n"},
+		{name: "Unknown key", data: "acount:\n  email: user@example.com\n"},
+		{name: "Invalid YAML", data: "account: [\n"},
+		{name: "Negative index", data: "queue:\n  index: -1\n"},
+		{name: "Invalid date", data: "preferences:\n  earliest_date: 03.10.2025\n"},
+		{name: "Invalid time", data: "preferences:\n  latest_time: 25:00\n"},
+		{name: "Reversed dates", data: "preferences:\n  earliest_date: 2025-11-01\n  latest_date: 2025-10-01\n"},
+		{name: "Invalid duration", data: "polling:\n  interval: often\n"},
+		{name: "Unknown notification type", data: "notifications:\n  - type: sms\n"},
+		{name: "Webhook without url", data: "notifications:\n  - type: webhook\n"},
+		{name: "File without path", data: "notifications:\n  - type: file\n"},
+		{name: "Invalid base url", data: "http:\n  base_url: inpol\n"},
+		{name: "Unknown rate limit family", data: "http:\n  rate_limits:\n    everything:\n      per_minute: 1\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cfg, err := Parse([]byte(tc.data))
+			assert.Error(t, err)
+			assert.Nil(t, cfg)
+		})
+	}
+}
+
+func TestParseAccounts(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := Parse([]byte(`
+accounts:
+  - alias: anna
+  - email: jan@example.com
+    password_file: /run/secrets/jan
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []AccountConfig{
+		{Alias: "anna"},
+		{Email: "jan@example.com", PasswordFile: "/run/secrets/jan"},
+	}, cfg.Accounts)
+}
+
+func TestProceedingSelector(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "", ProceedingConfig{}.Selector())
+	assert.Equal(t, "all", ProceedingConfig{All: true}.Selector())
+	assert.Equal(t, "id:proc-1", ProceedingConfig{ID: "proc-1"}.Selector())
+	assert.Equal(t, "signature:WSC-1", ProceedingConfig{Signature: "WSC-1"}.Selector())
+	assert.Equal(t, "type:Temporary residence permit", ProceedingConfig{Type: "Temporary residence permit"}.Selector())
+}
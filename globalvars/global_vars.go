@@ -1,28 +1,51 @@
 package globalvars
 
+import (
+	"bot-main/models"
+	"time"
+)
+
 var (
+	ConfigPath            = ""
 	Email                 = ""
 	Password              = ""
+	PasswordFile          = ""
+	Account               = ""
+	VaultFile             = DefaultVaultFile
 	ProceedingsCheckIndex = 0
+	Proceeding            = ""
+	QueueIndex            = 0
+	QueuePreferences      []string
+	WatchInterval         = 30 * time.Second
+	LookupConcurrency     = 4
+	MaxReserveAttempts    = 3
+	AllowDoubleBooking    = false
+	Reschedule            = false
+	DryRun                = false
+	RescheduleMargin      = 24 * time.Hour
+	CurrentAppointment    = ""
+	MaxRelogins           = 3
+	ForbiddenRetryBudget  = 10 * time.Second
+	DailyRequestBudget    = 1500
+	BudgetFile            = "budget.json"
+	BaseUrl               = ""
+	HTTPTimeout           = time.Minute
+	SlotStrategy          models.SlotStrategy
+
+	// Set from the configuration file only.
+	Preferences   = models.SlotPreferences{SkipPublicHolidays: true}
+	Notifications []models.NotificationSink
+	Accounts      []models.AccountSource
+	RateLimits    map[string]models.RateLimit
+
+	DefaultVaultFile = "vault.json"
 
 	ApplicationJson  = "application/json"
 	DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36"
-	Origin           = "https://inpol.mazowieckie.pl"
-	LoginPageUrl     = "https://inpol.mazowieckie.pl/login"
-	HomePageUrl      = "https://inpol.mazowieckie.pl/home"
-	HomePageCasesUrl = "https://inpol.mazowieckie.pl/home/cases/%s"
-
-	HtmlAcceptHeader     = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
-	KeepAliveHeader      = "keep-alive"
-	AcceptEncodingHeader = "gzip, deflate, br, zstd"
 
-	LoginRequestUrl                          = "https://inpol.mazowieckie.pl/identity/sign-in"
-	GetActiveProceedingsRequestUrl           = "https://inpol.mazowieckie.pl/api/foreigner/active-proceedings"
-	GetProceedingRequestUrl                  = "https://inpol.mazowieckie.pl/api/proceedings/%s" // GetProceedingRequestUrl = "https://inpol.mazowieckie.pl/api/proceedings/:proceedingId"
-	GetProceedingReservationQueuesRequestUrl = "https://inpol.mazowieckie.pl/api/proceedings/%s/reservationQueues"
-	GetReservationQueueDatesRequestUrl       = "https://inpol.mazowieckie.pl/api/reservations/queue/%s/dates"
-	GetReservationQueueDateSlotsRequestUrl   = "https://inpol.mazowieckie.pl/api/reservations/queue/%s/%s/slots" // reservations/queue/:queueId/:date/slots
-	ReserveAppointmentRequestUrl             = "https://inpol.mazowieckie.pl/api/reservations/queue/%s/reserve"  // reservations/queue/:queueId/reserve
+	// Accept-Encoding header is set by the transport according to what it can decode.
+	HtmlAcceptHeader = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	KeepAliveHeader  = "keep-alive"
 
 	AppointmentMade = "AppointmentMade"
 	Created         = "Created"
@@ -1,16 +1,74 @@
 package main
 
 import (
+	modelerrors "bot-main/models/errors"
 	"bot-main/requests"
 	"bot-main/utils"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
 	// Look at ability to use https://github.com/fatih/color
+	if len(os.Args) > 1 && os.Args[1] == "vault" {
+		if err := utils.RunVaultCommand(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Println("Starting the bot, press Ctrl+C to stop it at any time.")
 	fmt.Println("Reading input data...")
 	utils.RegisterCommandLineArgs()
-	applicationData := utils.ReadRequiredApplicationData()
-	requests.RequestPipeline(applicationData)
+	if err := utils.ApplyConfigFile(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	accounts, err := utils.ReadRequiredApplicationData()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// Restoring default signal behaviour, so the second Ctrl+C kills the bot at once.
+		stop()
+		fmt.Println()
+		fmt.Println("Stop requested, finishing current step, press Ctrl+C again to kill the bot immediately...")
+	}()
+
+	results := requests.RunAccounts(ctx, accounts, os.Stdout)
+	failed := false
+	for _, result := range results {
+		if len(results) > 1 {
+			fmt.Println()
+			fmt.Printf("Account %s:", result.AccountName)
+		}
+		result.Summary.Print(os.Stdout)
+		if result.Err == nil {
+			continue
+		}
+		if errors.Is(result.Err, context.Canceled) {
+			fmt.Println("Bot was stopped.")
+			continue
+		}
+		var unconfirmedError modelerrors.UnconfirmedReservationError
+		if errors.As(result.Err, &unconfirmedError) {
+			fmt.Printf("Reservation is unconfirmed: %v\n", result.Err)
+		} else {
+			fmt.Printf("Bot failed: %v\n", result.Err)
+		}
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
 }
@@ -1,5 +1,10 @@
 package errors
 
+import (
+	"bot-main/models"
+	"time"
+)
+
 type InvalidCredentailsError struct {
 	Message string
 }
@@ -10,20 +15,102 @@ func (e InvalidCredentailsError) Error() string {
 
 type UnauthorizedError struct {
 	Message string
+	// Response of the portal, nil when not known.
+	Portal *PortalError
 }
 
 func (e UnauthorizedError) Error() string {
 	return e.Message
 }
 
+func (e UnauthorizedError) Unwrap() error {
+	return portalCause(e.Portal)
+}
+
 type ForbiddenError struct {
 	Message string
+	// Response of the portal, nil when not known.
+	Portal *PortalError
 }
 
 func (e ForbiddenError) Error() string {
 	return e.Message
 }
 
+func (e ForbiddenError) Unwrap() error {
+	return portalCause(e.Portal)
+}
+
+// SlotTakenError means somebody else has booked the slot first.
+type SlotTakenError struct {
+	Message string
+	// Response of the portal, nil when not known.
+	Portal *PortalError
+}
+
+func (e SlotTakenError) Error() string {
+	return e.Message
+}
+
+func (e SlotTakenError) Unwrap() error {
+	return portalCause(e.Portal)
+}
+
+// InvalidPersonDataError means the portal rejected the reservation because
+// of the person data of the proceeding, trying other slots won't help.
+type InvalidPersonDataError struct {
+	Message string
+	Portal  *PortalError
+}
+
+func (e InvalidPersonDataError) Error() string {
+	return e.Message
+}
+
+func (e InvalidPersonDataError) Unwrap() error {
+	return portalCause(e.Portal)
+}
+
+// ProceedingNotEligibleError means appointments can't be made for
+// the proceeding at the moment.
+type ProceedingNotEligibleError struct {
+	Message string
+	Portal  *PortalError
+}
+
+func (e ProceedingNotEligibleError) Error() string {
+	return e.Message
+}
+
+func (e ProceedingNotEligibleError) Unwrap() error {
+	return portalCause(e.Portal)
+}
+
+// AppointmentExistsError means the proceeding already has an appointment,
+// booked by the bot before, another process or by hand.
+type AppointmentExistsError struct {
+	Message      string
+	ProceedingID string
+	// Date of the AppointmentMade timeline event, it's not known whether
+	// the portal puts the appointment date or the booking time there.
+	EventDate time.Time
+}
+
+func (e AppointmentExistsError) Error() string {
+	return e.Message
+}
+
+// UnconfirmedReservationError means the portal accepted the reservation
+// request, but the appointment didn't show up in the proceeding timeline.
+type UnconfirmedReservationError struct {
+	Message string
+	Slot    models.Slot
+}
+
+func (e UnconfirmedReservationError) Error() string {
+	return e.Message
+}
+
 type ProceedingsCountError struct {
 	Message string
 }
@@ -31,3 +118,30 @@ type ProceedingsCountError struct {
 func (e ProceedingsCountError) Error() string {
 	return e.Message
 }
+
+type UnsupportedContentEncodingError struct {
+	Message  string
+	Encoding string
+}
+
+func (e UnsupportedContentEncodingError) Error() string {
+	return e.Message
+}
+
+type BudgetExhaustedError struct {
+	Message string
+	// When the budget is available again.
+	ResetAt time.Time
+}
+
+func (e BudgetExhaustedError) Error() string {
+	return e.Message
+}
+
+type InvalidVaultPassphraseError struct {
+	Message string
+}
+
+func (e InvalidVaultPassphraseError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,104 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kinds of portal failures, a PortalError unwraps to its kind,
+// so errors.Is(err, ErrRateLimited) tells the failure apart.
+var (
+	ErrRateLimited       = errors.New("rate limited")
+	ErrServerUnavailable = errors.New("server unavailable")
+	ErrMaintenance       = errors.New("maintenance")
+	ErrSlotTaken         = errors.New("slot taken")
+	ErrSessionExpired    = errors.New("session expired")
+)
+
+// MaxPortalErrorBody is how much of a response body a PortalError keeps.
+const MaxPortalErrorBody = 512
+
+// Words of the portal maintenance page.
+var maintenanceHints = []string{"maintenance", "przerwa techniczna", "prace serwisowe"}
+
+// PortalError is a response of the portal with an unexpected status.
+type PortalError struct {
+	Message    string
+	Endpoint   string
+	StatusCode int
+	// Beginning of the response body, up to MaxPortalErrorBody bytes.
+	Body string
+	// Whether the same request may be sent again later. It's false when
+	// the portal failed a request which isn't idempotent, as it may have
+	// been applied already.
+	Retryable bool
+	// One of the kinds above, nil when the failure isn't any of them.
+	Kind error
+}
+
+// NewPortalError classifies the response of the endpoint by its status
+// and body. Server errors of requests which aren't idempotent aren't
+// retryable.
+func NewPortalError(endpoint string, idempotent bool, statusCode int, body []byte, message string) PortalError {
+	portalError := PortalError{
+		Message:    message,
+		Endpoint:   endpoint,
+		StatusCode: statusCode,
+		Body:       truncateBody(body),
+	}
+	switch statusCode {
+	case http.StatusUnauthorized:
+		portalError.Kind, portalError.Retryable = ErrSessionExpired, true
+	case http.StatusConflict:
+		portalError.Kind = ErrSlotTaken
+	case http.StatusTooManyRequests:
+		portalError.Kind, portalError.Retryable = ErrRateLimited, true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		portalError.Kind, portalError.Retryable = ErrServerUnavailable, idempotent
+		if maintenance(body) {
+			portalError.Kind = ErrMaintenance
+		}
+	}
+	return portalError
+}
+
+func (e PortalError) Error() string {
+	return e.Message
+}
+
+func (e PortalError) Unwrap() error {
+	return e.Kind
+}
+
+func maintenance(body []byte) bool {
+	text := strings.ToLower(string(body))
+	for _, hint := range maintenanceHints {
+		if strings.Contains(text, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateBody keeps the body up to MaxPortalErrorBody bytes, not cutting
+// a character in half.
+func truncateBody(body []byte) string {
+	if len(body) <= MaxPortalErrorBody {
+		return string(body)
+	}
+	end := MaxPortalErrorBody
+	for end > 0 && !utf8.RuneStart(body[end]) {
+		end--
+	}
+	return string(body[:end]) + "..."
+}
+
+// portalCause lets typed errors carrying a PortalError unwrap to it.
+func portalCause(portalError *PortalError) error {
+	if portalError == nil {
+		return nil
+	}
+	return *portalError
+}
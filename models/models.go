@@ -1,10 +1,137 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type ApplicationData struct {
+	// Name of the account in output, its vault alias or email.
+	AccountName           string
 	LoginData             LoginData
 	ProceedingsCheckIndex int
+	// Which active proceedings are watched, by ProceedingsCheckIndex when empty.
+	ProceedingSelector ProceedingSelector
+	QueueIndex         int
+	// Queues watched in order of preference, by QueueIndex when empty.
+	QueuePreferences []string
+	Preferences      SlotPreferences
+	// How free slots are ranked before booking.
+	SlotStrategy  SlotStrategy
+	Notifications []NotificationSink
+	// Pause between two consecutive dates/slots checks in watch mode.
+	WatchInterval time.Duration
+	// How many date slots are looked up at once.
+	LookupConcurrency int
+	// How many slots are tried in one watch attempt when they turn out to be
+	// already taken.
+	MaxReserveAttempts int
+	// Whether a proceeding which already has an appointment may be booked again.
+	AllowDoubleBooking bool
+	// Whether the reservation request is only printed instead of being sent.
+	DryRun bool
+	// Whether only slots earlier than the current appointment are booked.
+	Reschedule bool
+	// How much earlier than the current appointment a slot must start.
+	RescheduleMargin time.Duration
+	// Current appointment as 2006-01-02 15:04 in Warsaw time, the date of
+	// the AppointmentMade event is used when empty.
+	CurrentAppointment string
+	// How many times in a row the bot may log in again after the session
+	// expired before giving up.
+	MaxConsecutiveRelogins int
+	// How long a forbidden reservation is retried with refreshed cookies.
+	ForbiddenRetryBudget time.Duration
+	// Overrides of default rate limits by endpoint family name
+	// ("login", "proceedings", "dates", "slots", "reserve").
+	RateLimits map[string]RateLimit
+	// Max requests per day for the account, zero means no limit.
+	DailyRequestBudget int
+	// File where daily budget usage is kept between restarts.
+	BudgetFile string
+	// Portal address, inpol.DefaultBaseUrl when empty.
+	BaseUrl string
+	// Timeout of a single HTTP request, zero means no timeout.
+	HTTPTimeout time.Duration
+}
+
+// Ways active proceedings can be selected by.
+const (
+	SelectProceedingByIndex     = "index"
+	SelectProceedingByID        = "id"
+	SelectProceedingBySignature = "signature"
+	SelectProceedingByType      = "type"
+	SelectAllProceedings        = "all"
+)
+
+// ProceedingSelector chooses active proceedings, Value is the ID, signature
+// or type (its ID or name) according to Kind.
+type ProceedingSelector struct {
+	Kind  string
+	Value string
+}
+
+// SlotPreferences limit which slots may be reserved, empty values are not checked.
+type SlotPreferences struct {
+	// Dates as 2006-01-02.
+	EarliestDate string
+	LatestDate   string
+	// Days of week slots may be on, any day when empty.
+	Weekdays []time.Weekday
+	// Dates as 2006-01-02 which must not be booked.
+	BlackoutDates []string
+	// Whether Polish public holidays are skipped.
+	SkipPublicHolidays bool
+	// Times of day as 15:04.
+	EarliestTime string
+	LatestTime   string
+	// Slots must start inside one of the windows, any time when empty.
+	TimeWindows []TimeWindow
+}
+
+// SlotStrategy chooses how free slots are ranked before booking.
+type SlotStrategy struct {
+	// One of "earliest", "latest", "nearest" or "weighted", earliest when empty.
+	Name string
+	// Date as 2006-01-02 the nearest and weighted strategies aim for.
+	TargetDate string
+	// Time of day as 15:04 the weighted strategy aims for.
+	PreferredTime string
+	// Weighted strategy score per day from the target, per hour from
+	// the preferred time and per position of the queue in preferences.
+	DateWeight  float64
+	HourWeight  float64
+	QueueWeight float64
+}
+
+// TimeWindow is a part of the day, in Warsaw time, on some days of week.
+type TimeWindow struct {
+	// Every day when empty.
+	Weekdays []time.Weekday
+	// Times of day as 15:04, the end is exclusive.
+	Start string
+	End   string
+}
+
+type NotificationSink struct {
+	// One of "console", "webhook" or "file".
+	Type string
+	Url  string
+	Path string
+}
+
+type RateLimit struct {
+	PerMinute float64
+	Burst     int
+}
+
+// AccountSource tells where login data of one of several accounts comes from,
+// either a vault alias or an email with a password or a password file.
+type AccountSource struct {
+	Alias        string
+	Email        string
+	Password     string
+	PasswordFile string
 }
 
 type LoginData struct {
@@ -12,6 +139,15 @@ type LoginData struct {
 	Password string
 }
 
+// String keeps the password out of logs when login data is printed.
+func (l LoginData) String() string {
+	return fmt.Sprintf("{Email:%s Password:********}", l.Email)
+}
+
+func (l LoginData) GoString() string {
+	return l.String()
+}
+
 type LoginPayload struct {
 	Email         string `json:"email"`
 	Password      string `json:"password"`
@@ -26,6 +162,23 @@ type ReservePayload struct {
 	DateOfBirth  string `json:"dateOfBirth"` // ISO8601
 }
 
+// ReservationResult is the reply to an accepted reservation. Field names
+// are assumed from other portal replies, missing ones stay empty.
+type ReservationResult struct {
+	ConfirmationID string `json:"id"`
+	ProceedingID   string `json:"proceedingId"`
+	SlotID         int64  `json:"slotId"`
+	Date           string `json:"date"`
+	Localization   string `json:"localization"`
+	QueueID        string `json:"queueId"`
+}
+
+// ReservationErrorResponse is the body of a rejected reservation.
+type ReservationErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type LoginResponse struct {
 	IsAuthSuccessful bool    `json:"isAuthSuccessful"`
 	ErrorMessage     any     `json:"errorMessage"`
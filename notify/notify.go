@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"bot-main/models"
+	"bot-main/output"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	EventSlotReserved      = "slot_reserved"
+	EventReservationFailed = "reservation_failed"
+	EventAppointmentExists = "appointment_exists"
+	// Reservation request was accepted, but the appointment can't be found.
+	EventReservationUnconfirmed = "reservation_unconfirmed"
+	EventPipelineFailed         = "pipeline_failed"
+)
+
+type Event struct {
+	Time    time.Time `json:"time"`
+	Account string    `json:"account"`
+	Kind    string    `json:"kind"`
+	Message string    `json:"message"`
+}
+
+// Sink delivers events somewhere the operator will see them.
+type Sink interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Notifier sends events of one account to all configured sinks.
+type Notifier struct {
+	account string
+	sinks   []Sink
+}
+
+func New(account string, sinkConfigs []models.NotificationSink) *Notifier {
+	notifier := &Notifier{account: account}
+	for _, sinkConfig := range sinkConfigs {
+		switch sinkConfig.Type {
+		case "console":
+			notifier.sinks = append(notifier.sinks, ConsoleSink{})
+		case "webhook":
+			notifier.sinks = append(notifier.sinks, &WebhookSink{
+				Url:    sinkConfig.Url,
+				Client: &http.Client{Timeout: 10 * time.Second},
+			})
+		case "file":
+			notifier.sinks = append(notifier.sinks, &FileSink{Path: sinkConfig.Path})
+		}
+	}
+	return notifier
+}
+
+// Notify sends the event to every sink. Failed deliveries are only printed,
+// notifications must never stop the bot.
+func (n *Notifier) Notify(ctx context.Context, kind, message string) {
+	if n == nil {
+		return
+	}
+	event := Event{
+		Time:    time.Now(),
+		Account: n.account,
+		Kind:    kind,
+		Message: message,
+	}
+	for _, sink := range n.sinks {
+		if err := sink.Notify(ctx, event); err != nil {
+			output.Printf(ctx, "Notifier error sending %s event to %T: %v\n", kind, sink, err)
+		}
+	}
+}
+
+type ConsoleSink struct{}
+
+func (ConsoleSink) Notify(ctx context.Context, event Event) error {
+	output.Printf(ctx, "🔔 [%s] %s: %s\n", event.Account, event.Kind, event.Message)
+	return nil
+}
+
+// WebhookSink posts events as JSON.
+type WebhookSink struct {
+	Url    string
+	Client *http.Client
+}
+
+func (s *WebhookSink) Notify(ctx context.Context, event Event) error {
+	payloadBytes, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("WebhookSink error encoding JSON: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", s.Url, bytes.NewReader(payloadBytes))
+	if err != nil {
+		return fmt.Errorf("WebhookSink error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("WebhookSink request error executing: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("WebhookSink request failed with status: %s", resp.Status)
+	}
+	return nil
+}
+
+// FileSink appends events to a file as JSON lines.
+type FileSink struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (s *FileSink) Notify(ctx context.Context, event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("FileSink error encoding JSON: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("FileSink error opening %s: %w", s.Path, err)
+	}
+	defer file.Close()
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("FileSink error writing %s: %w", s.Path, err)
+	}
+	return nil
+}
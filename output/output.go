@@ -0,0 +1,96 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+type writerKey struct{}
+
+// WithWriter makes everything printed with ctx go to w instead of stdout.
+func WithWriter(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, writerKey{}, w)
+}
+
+// Writer returns the writer of ctx, stdout by default.
+func Writer(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(writerKey{}).(io.Writer); ok {
+		return w
+	}
+	return os.Stdout
+}
+
+func Printf(ctx context.Context, format string, args ...any) {
+	fmt.Fprintf(Writer(ctx), format, args...)
+}
+
+func Println(ctx context.Context, args ...any) {
+	fmt.Fprintln(Writer(ctx), args...)
+}
+
+// SyncWriter serializes writes of several goroutines to one writer.
+type SyncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewSyncWriter(w io.Writer) *SyncWriter {
+	return &SyncWriter{w: w}
+}
+
+func (s *SyncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// PrefixWriter starts every line with the prefix, so output of concurrently
+// running accounts can be told apart. Lines are written whole, an unfinished
+// line waits for its end or Flush.
+type PrefixWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func NewPrefixWriter(w io.Writer, prefix string) *PrefixWriter {
+	return &PrefixWriter{w: w, prefix: prefix}
+}
+
+func (p *PrefixWriter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, data...)
+	for {
+		end := bytes.IndexByte(p.buf, '\n')
+		if end < 0 {
+			return len(data), nil
+		}
+		if err := p.writeLine(p.buf[:end+1]); err != nil {
+			return len(data), err
+		}
+		p.buf = p.buf[end+1:]
+	}
+}
+
+// Flush writes the unfinished line, if any.
+func (p *PrefixWriter) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.buf) == 0 {
+		return nil
+	}
+	err := p.writeLine(append(p.buf, '\n'))
+	p.buf = nil
+	return err
+}
+
+func (p *PrefixWriter) writeLine(line []byte) error {
+	_, err := p.w.Write(append([]byte(p.prefix), line...))
+	return err
+}
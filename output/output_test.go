@@ -0,0 +1,32 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w := NewPrefixWriter(&buf, "[anna] ")
+	ctx := WithWriter(context.Background(), w)
+
+	Printf(ctx, "first %s", "line")
+	assert.Empty(t, buf.String())
+	Println(ctx, " continues")
+	Printf(ctx, "\nafter empty line\nunfinished")
+	assert.NoError(t, w.Flush())
+
+	assert.Equal(t, "[anna] first line continues\n[anna] \n[anna] after empty line\n[anna] unfinished\n", buf.String())
+}
+
+func TestWriterDefaultsToStdout(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, os.Stdout, Writer(context.Background()))
+}
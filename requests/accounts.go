@@ -0,0 +1,60 @@
+package requests
+
+import (
+	"bot-main/models"
+	"bot-main/output"
+	"context"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sync"
+)
+
+// AccountResult is how the pipeline of one account ended.
+type AccountResult struct {
+	AccountName string
+	Summary     *Summary
+	Err         error
+}
+
+// RunAccounts runs a pipeline per account concurrently, each with its own
+// client, cookies and token. Output lines are prefixed with the account name
+// when there is more than one account. A failure or even a panic of one
+// pipeline doesn't stop the others, results are returned when all are done.
+func RunAccounts(ctx context.Context, accounts []models.ApplicationData, w io.Writer) []AccountResult {
+	return runAccounts(ctx, accounts, w, RequestPipeline)
+}
+
+type pipelineFunc func(ctx context.Context, applicationData models.ApplicationData, summary *Summary) error
+
+func runAccounts(ctx context.Context, accounts []models.ApplicationData, w io.Writer, pipeline pipelineFunc) []AccountResult {
+	results := make([]AccountResult, len(accounts))
+	syncWriter := output.NewSyncWriter(w)
+	var wg sync.WaitGroup
+	for i, applicationData := range accounts {
+		results[i] = AccountResult{AccountName: applicationData.AccountName, Summary: NewSummary()}
+		wg.Add(1)
+		go func(result *AccountResult) {
+			defer wg.Done()
+			accountCtx := output.WithWriter(ctx, syncWriter)
+			if len(accounts) > 1 {
+				prefixWriter := output.NewPrefixWriter(syncWriter, fmt.Sprintf("[%s] ", applicationData.AccountName))
+				defer prefixWriter.Flush()
+				accountCtx = output.WithWriter(ctx, prefixWriter)
+			}
+			result.Err = runAccount(accountCtx, applicationData, result.Summary, pipeline)
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+func runAccount(ctx context.Context, applicationData models.ApplicationData, summary *Summary, pipeline pipelineFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			output.Printf(ctx, "RequestPipeline panicked: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("❌ RequestPipeline panicked: %v", r)
+		}
+	}()
+	return pipeline(ctx, applicationData, summary)
+}
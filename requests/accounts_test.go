@@ -0,0 +1,64 @@
+package requests
+
+import (
+	"bot-main/models"
+	"bot-main/output"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunAccountsIsolatesAccounts(t *testing.T) {
+	t.Parallel()
+
+	accounts := []models.ApplicationData{
+		{AccountName: "anna"},
+		{AccountName: "jan"},
+		{AccountName: "ola"},
+	}
+	var out bytes.Buffer
+	results := runAccounts(context.Background(), accounts, &out, func(ctx context.Context, applicationData models.ApplicationData, summary *Summary) error {
+		switch applicationData.AccountName {
+		case "anna":
+			output.Printf(ctx, "reserving...\n")
+			summary.reservationFinished(models.Slot{ID: 1}, true)
+			return nil
+		case "jan":
+			panic("broken response")
+		default:
+			output.Printf(ctx, "unfinished line")
+			return errors.New("portal is down")
+		}
+	})
+
+	assert.Len(t, results, 3)
+	assert.Equal(t, "anna", results[0].AccountName)
+	assert.NoError(t, results[0].Err)
+	assert.NotEmpty(t, results[0].Summary.reservedSlots)
+	assert.ErrorContains(t, results[1].Err, "broken response")
+	assert.Empty(t, results[1].Summary.reservedSlots)
+	assert.EqualError(t, results[2].Err, "portal is down")
+
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		assert.Regexp(t, `^\[(anna|jan|ola)\] `, line)
+	}
+	assert.Contains(t, out.String(), "[anna] reserving...\n")
+	assert.Contains(t, out.String(), "[ola] unfinished line\n")
+}
+
+func TestRunAccountsSingleAccountWithoutPrefix(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	results := runAccounts(context.Background(), []models.ApplicationData{{AccountName: "anna"}}, &out, func(ctx context.Context, applicationData models.ApplicationData, summary *Summary) error {
+		output.Printf(ctx, "hello\n")
+		return nil
+	})
+
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "hello\n", out.String())
+}
@@ -1,11 +1,11 @@
 package activeproceedings
 
 import (
-	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
 	test_utils "bot-main/tests/utils"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -16,8 +16,7 @@ import (
 )
 
 func TestGetActiveProceedings(t *testing.T) {
-	globalvars.GetActiveProceedingsRequestUrl = "https://fake/active"
-	globalvars.HomePageUrl = "https://fake/home"
+	t.Parallel()
 
 	testCases := []struct {
 		name         string
@@ -30,7 +29,7 @@ func TestGetActiveProceedings(t *testing.T) {
 		{
 			name:       "nil client",
 			client:     nil,
-			wantErrStr: "HTTP client is nil",
+			wantErrStr: "client is nil",
 		},
 		{
 			name: "unauthorized",
@@ -91,7 +90,8 @@ func TestGetActiveProceedings(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := GetActiveProceedings(tc.client, tc.sessionToken)
+			t.Parallel()
+			result, err := GetActiveProceedings(context.Background(), test_utils.NewInpolClient(tc.client, tc.sessionToken))
 
 			if tc.wantErrStr != "" {
 				assert.Error(t, err)
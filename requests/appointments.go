@@ -0,0 +1,181 @@
+package requests
+
+import (
+	"bot-main/globalvars"
+	"bot-main/models"
+	modelerrors "bot-main/models/errors"
+	"bot-main/output"
+	"bot-main/requests/proceeding"
+	"bot-main/selection"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// appointmentCheckEvery is how many watch attempts pass between checks whether
+// the watched proceedings got an appointment some other way. Every check costs
+// one more request of the daily budget per proceeding.
+const appointmentCheckEvery = 10
+
+// How long a made reservation is looked for in the proceeding timeline
+// and the pause between two checks.
+const (
+	reservationVerifyWindow = 30 * time.Second
+	reservationVerifyPause  = 5 * time.Second
+)
+
+// madeAppointment returns the AppointmentMade event of the proceeding with
+// the latest date.
+func madeAppointment(proceedingData *models.DetailedProceedingData) (models.Event, bool) {
+	var appointment models.Event
+	found := false
+	for _, event := range proceedingData.TimelineEvents {
+		if event.EventType != globalvars.AppointmentMade {
+			continue
+		}
+		if !found || event.Date.After(appointment.Date) {
+			appointment = event
+			found = true
+		}
+	}
+	return appointment, found
+}
+
+// appointmentExistsError is returned instead of booking a proceeding
+// which already has an appointment.
+func appointmentExistsError(proceedingID string, appointment models.Event) error {
+	return modelerrors.AppointmentExistsError{
+		Message: fmt.Sprintf("⚠️ Proceeding %s already has an appointment (%s event dated %s), not booking another one",
+			proceedingID, globalvars.AppointmentMade, appointment.Date.Format(time.DateTime)),
+		ProceedingID: proceedingID,
+		EventDate:    appointment.Date,
+	}
+}
+
+// currentAppointment gives the appointment to reschedule: the one set by
+// the operator or else the date of the AppointmentMade event. Zero means
+// there is nothing to reschedule.
+func currentAppointment(ctx context.Context, applicationData models.ApplicationData, proceedingID string, appointment models.Event, found bool) time.Time {
+	if applicationData.CurrentAppointment != "" {
+		// Validated when application data was read.
+		current, _ := selection.ParseAppointmentTime(applicationData.CurrentAppointment)
+		return current
+	}
+	if !found {
+		return time.Time{}
+	}
+	output.Printf(ctx, "⚠️ RequestPipeline, date %s of the %s event of proceeding %s is taken as the current appointment, set the current appointment explicitly if the portal keeps the booking time there.\n",
+		appointment.Date.In(selection.Warsaw).Format(selection.AppointmentLayout), globalvars.AppointmentMade, proceedingID)
+	return appointment.Date
+}
+
+// checkNoAppointment gets the proceeding details again, so an appointment
+// booked meanwhile by another process or by hand stops the watch. Nothing
+// is checked when double booking is allowed or the target is rescheduled.
+func (w *watcher) checkNoAppointment(ctx context.Context, target watchTarget) error {
+	if w.applicationData.AllowDoubleBooking || target.rescheduling() {
+		return nil
+	}
+	proceedingID := target.proceedingData.ID
+	proceedingData, err := w.getProceedingData(ctx, proceedingID)
+	if err != nil {
+		return err
+	}
+	if appointment, found := madeAppointment(proceedingData); found {
+		return appointmentExistsError(proceedingID, appointment)
+	}
+	return nil
+}
+
+// verifyReservation gets the proceeding details again until a new
+// AppointmentMade event dated as the reserved slot shows up in its timeline
+// or the verify window passes. A 200 of the reservation request alone isn't
+// trusted, the reservation is reported as unconfirmed then.
+func (w *watcher) verifyReservation(ctx context.Context, target watchTarget, queue models.ReservationQueue, slot models.Slot) error {
+	// Reserved slots passed SlotAllowed, so their dates parse.
+	slotTime, _ := selection.ParseSlotTime(slot.Date)
+	deadline := time.Now().Add(w.verifyWindow)
+	var reason string
+	for check := 1; ; check++ {
+		output.Printf(ctx, "RequestPipeline, verifying reservation of %s for proceeding %s (check %d)...\n", slot.Date, target.proceedingData.ID, check)
+		proceedingData, err := w.getProceedingData(ctx, target.proceedingData.ID)
+		if err == nil {
+			appointments := newAppointments(target.proceedingData.TimelineEvents, proceedingData.TimelineEvents)
+			if slices.ContainsFunc(appointments, func(event models.Event) bool { return event.Date.Equal(slotTime) }) {
+				output.Printf(ctx, "✅ RequestPipeline, reservation of %s at %s is confirmed by the proceeding timeline.\n", slot.Date, queue.Localization)
+				return nil
+			}
+			reason = fmt.Sprintf("no new %s event in the proceeding timeline", globalvars.AppointmentMade)
+			if len(appointments) > 0 {
+				reason = fmt.Sprintf("new %s event is dated %s instead of %s",
+					globalvars.AppointmentMade, appointments[0].Date.Format(time.RFC3339), slotTime.Format(time.RFC3339))
+			}
+		} else {
+			reason = err.Error()
+		}
+		if ctx.Err() != nil || !time.Now().Add(reservationVerifyPause).Before(deadline) {
+			break
+		}
+		output.Printf(ctx, "RequestPipeline, reservation isn't confirmed yet (%s), checking again in %s...\n", reason, reservationVerifyPause)
+		if sleep(ctx, reservationVerifyPause) != nil {
+			break
+		}
+	}
+	return modelerrors.UnconfirmedReservationError{
+		Message: fmt.Sprintf("⚠️ Reservation of slot %s at %s for proceeding %s is unconfirmed: %s, check the portal",
+			slot.Date, queue.Localization, target.proceedingData.ID, reason),
+		Slot: slot,
+	}
+}
+
+// checkUnknownReservation looks for the reservation which outcome wasn't
+// known in the proceeding timeline. Any other new appointment stops the watch
+// too, the portal may have dated the reservation differently.
+func (w *watcher) checkUnknownReservation(ctx context.Context, target watchTarget, candidate selection.Candidate) (bool, error) {
+	output.Printf(ctx, "RequestPipeline, checking whether the reservation of %s for proceeding %s was made...\n", candidate.Slot.Date, target.proceedingData.ID)
+	proceedingData, err := w.getProceedingData(ctx, target.proceedingData.ID)
+	if err != nil {
+		return false, err
+	}
+	appointments := newAppointments(target.proceedingData.TimelineEvents, proceedingData.TimelineEvents)
+	if slices.ContainsFunc(appointments, func(event models.Event) bool { return event.Date.Equal(candidate.Time) }) {
+		output.Printf(ctx, "✅ RequestPipeline, reservation of %s at %s was made after all.\n", candidate.Slot.Date, candidate.Queue.Localization)
+		return true, nil
+	}
+	if len(appointments) > 0 {
+		return false, appointmentExistsError(target.proceedingData.ID, appointments[0])
+	}
+	output.Printf(ctx, "RequestPipeline, reservation of %s wasn't made, watching again.\n", candidate.Slot.Date)
+	return false, nil
+}
+
+// newAppointments returns AppointmentMade events of after which aren't in before.
+func newAppointments(before, after []models.Event) []models.Event {
+	var appointments []models.Event
+	for _, event := range after {
+		if event.EventType != globalvars.AppointmentMade {
+			continue
+		}
+		known := slices.ContainsFunc(before, func(old models.Event) bool {
+			return old.EventType == event.EventType && old.Date.Equal(event.Date)
+		})
+		if !known {
+			appointments = append(appointments, event)
+		}
+	}
+	return appointments
+}
+
+func (w *watcher) getProceedingData(ctx context.Context, proceedingID string) (*models.DetailedProceedingData, error) {
+	s := w.session
+	var proceedingData *models.DetailedProceedingData
+	err := s.call(ctx, "getting appointments of proceeding", func() (err error) {
+		proceedingData, err = proceeding.GetProceedingData(ctx, s.client, models.ActiveProceeding{ProceedingsID: proceedingID})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("RequestPipeline error during getting appointments of proceeding %s: %w", proceedingID, err)
+	}
+	return proceedingData, nil
+}
@@ -0,0 +1,229 @@
+package requests
+
+import (
+	"bot-main/models"
+	"bot-main/selection"
+	test_utils "bot-main/tests/utils"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const bookedProceeding = `{"id":"proc-1","canMakeAppointment":true,"timelineEvents":[
+	{"eventType":"Created","date":"2025-09-01T10:00:00Z"},
+	{"eventType":"AppointmentMade","date":"2025-09-10T10:00:00Z"}
+]}`
+
+func TestMadeAppointment(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		events        []models.Event
+		expectedDate  string
+		expectedFound bool
+	}{
+		{name: "No events"},
+		{name: "Other events", events: []models.Event{{EventType: "Created", Date: time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)}}},
+		{
+			name: "Latest of several",
+			events: []models.Event{
+				{EventType: "AppointmentMade", Date: time.Date(2025, 9, 10, 0, 0, 0, 0, time.UTC)},
+				{EventType: "AppointmentMade", Date: time.Date(2025, 11, 3, 0, 0, 0, 0, time.UTC)},
+				{EventType: "Created", Date: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)},
+				{EventType: "AppointmentMade", Date: time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)},
+			},
+			expectedDate:  "2025-11-03",
+			expectedFound: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			appointment, found := madeAppointment(&models.DetailedProceedingData{TimelineEvents: tc.events})
+			assert.Equal(t, tc.expectedFound, found)
+			if tc.expectedFound {
+				assert.Equal(t, tc.expectedDate, appointment.Date.Format(time.DateOnly))
+			}
+		})
+	}
+}
+
+func TestPrepareWatchTargetWithAppointment(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		allowDoubleBooking bool
+		expectedSkip       bool
+	}{
+		{name: "Skipped", expectedSkip: true},
+		{name: "Double booking allowed", allowDoubleBooking: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var signIns atomic.Int32
+			httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+				body := ""
+				switch req.URL.Path {
+				case "/api/proceedings/proc-1":
+					body = bookedProceeding
+				case "/api/proceedings/proc-1/reservationQueues":
+					body = `[{"id":"queue-1","localization":"Okopowa 7"}]`
+				default:
+					return statusResponse(http.StatusNotFound)
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+			})
+			applicationData := models.ApplicationData{AllowDoubleBooking: tc.allowDoubleBooking}
+			s := newSession(test_utils.NewInpolClient(httpClient, "tok"), applicationData)
+
+			target, skipReason, err := prepareWatchTarget(context.Background(), s, NewSummary(), applicationData, models.ActiveProceeding{ProceedingsID: "proc-1"})
+			assert.NoError(t, err)
+			if tc.expectedSkip {
+				assert.Contains(t, skipReason, "already has an appointment")
+				assert.Nil(t, target.proceedingData)
+				return
+			}
+			assert.Empty(t, skipReason)
+			assert.Equal(t, "proc-1", target.proceedingData.ID)
+			assert.Len(t, target.queues, 1)
+		})
+	}
+}
+
+func TestWatchAndReserveStopsWhenAppointmentMadeMeanwhile(t *testing.T) {
+	t.Parallel()
+
+	var signIns atomic.Int32
+	var reserveCalls atomic.Int32
+	httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+		body := ""
+		switch req.URL.Path {
+		case "/api/proceedings/proc-1":
+			// Booked by hand after the watch had started.
+			body = bookedProceeding
+		case "/api/reservations/queue/queue-1/dates":
+			body = `["2025-10-03"]`
+		case "/api/reservations/queue/queue-1/2025-10-03/slots":
+			body = `[{"id":1,"date":"2025-10-03T09:00:00","count":1}]`
+		case "/api/reservations/queue/queue-1/reserve":
+			reserveCalls.Add(1)
+		default:
+			return statusResponse(http.StatusNotFound)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+	})
+	s := newSession(test_utils.NewInpolClient(httpClient, "tok"), models.ApplicationData{})
+	summary := NewSummary()
+
+	reservedSlots, err := newTestWatcher(s, summary).watchAndReserve(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, reservedSlots)
+	assert.Equal(t, int32(0), reserveCalls.Load())
+	var printed strings.Builder
+	summary.Print(&printed)
+	assert.Contains(t, printed.String(), "Stopped watching proceeding proc-1")
+}
+
+func TestPrepareWatchTargetReschedule(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		currentAppointment string
+		expectedCurrent    string
+		expectedBookBefore string
+	}{
+		{name: "From timeline", expectedCurrent: "2025-09-10T12:00:00+02:00", expectedBookBefore: "2025-09-09T12:00:00+02:00"},
+		{name: "Set explicitly", currentAppointment: "2025-11-20 10:30", expectedCurrent: "2025-11-20T10:30:00+01:00", expectedBookBefore: "2025-11-19T10:30:00+01:00"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var signIns atomic.Int32
+			httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+				body := ""
+				switch req.URL.Path {
+				case "/api/proceedings/proc-1":
+					body = bookedProceeding
+				case "/api/proceedings/proc-1/reservationQueues":
+					body = `[{"id":"queue-1","localization":"Okopowa 7"}]`
+				default:
+					return statusResponse(http.StatusNotFound)
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+			})
+			applicationData := models.ApplicationData{Reschedule: true, RescheduleMargin: 24 * time.Hour, CurrentAppointment: tc.currentAppointment}
+			s := newSession(test_utils.NewInpolClient(httpClient, "tok"), applicationData)
+
+			target, skipReason, err := prepareWatchTarget(context.Background(), s, NewSummary(), applicationData, models.ActiveProceeding{ProceedingsID: "proc-1"})
+			assert.NoError(t, err)
+			assert.Empty(t, skipReason)
+			assert.True(t, target.rescheduling())
+			assert.Equal(t, tc.expectedCurrent, target.currentAppointment.In(selection.Warsaw).Format(time.RFC3339))
+			assert.Equal(t, tc.expectedBookBefore, target.bookBefore.In(selection.Warsaw).Format(time.RFC3339))
+		})
+	}
+}
+
+func TestWatchTargetReschedulesOnlyEarlierByMargin(t *testing.T) {
+	t.Parallel()
+
+	var signIns atomic.Int32
+	var lookedUp sync.Map
+	var reserved string
+	var timeline fakeTimeline
+	httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+		lookedUp.Store(req.URL.Path, true)
+		body := ""
+		switch req.URL.Path {
+		case "/api/proceedings/proc-1":
+			body = timeline.proceeding()
+		case "/api/reservations/queue/queue-1/dates":
+			body = `["2025-10-03","2025-10-04","2025-10-05"]`
+		case "/api/reservations/queue/queue-1/2025-10-03/slots":
+			body = `[{"id":1,"date":"2025-10-03T09:00:00","count":1}]`
+		case "/api/reservations/queue/queue-1/2025-10-04/slots":
+			body = `[{"id":2,"date":"2025-10-04T09:00:00","count":1},{"id":3,"date":"2025-10-04T11:00:00","count":1}]`
+		case "/api/reservations/queue/queue-1/reserve":
+			payload, _ := io.ReadAll(req.Body)
+			reserved = string(payload)
+			timeline.reserved("2025-10-04T09:00:00")
+		default:
+			return statusResponse(http.StatusNotFound)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+	})
+	s := newSession(test_utils.NewInpolClient(httpClient, "tok"), models.ApplicationData{})
+	summary := NewSummary()
+	w := newTestWatcher(s, summary)
+	// The latest slot would win, if it weren't too late.
+	w.selector = selection.LatestSelector{}
+	current, _ := selection.ParseAppointmentTime("2025-10-05 10:00")
+	target := testWatchTarget
+	target.currentAppointment = current
+	target.bookBefore = current.Add(-24 * time.Hour)
+
+	slot, err := w.watchTarget(context.Background(), target)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, slot.ID)
+	assert.Contains(t, reserved, `"slotId":2`)
+	_, checked := lookedUp.Load("/api/reservations/queue/queue-1/2025-10-05/slots")
+	assert.False(t, checked)
+	var printed strings.Builder
+	summary.Print(&printed)
+	assert.Contains(t, printed.String(), "Rescheduled proceeding proc-1 from 2025-10-05 10:00 to 2025-10-04 09:00")
+}
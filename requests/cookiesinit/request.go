@@ -2,41 +2,43 @@ package cookiesinit
 
 import (
 	"bot-main/globalvars"
+	"bot-main/output"
+	"bot-main/requests/inpol"
+	"context"
 	"fmt"
-	"log"
 	"net/http"
 )
 
-func CookiesInit(client *http.Client) error {
+func CookiesInit(ctx context.Context, client *inpol.Client) error {
 	if client == nil {
-		return fmt.Errorf("HTTP client is nil")
+		return fmt.Errorf("CookiesInit, client is nil")
 	}
 
-	fmt.Printf("CookiesInit, sending GET-request to %s to get cookie...\n", globalvars.LoginPageUrl)
+	loginPageUrl := client.LoginPageUrl()
+	output.Printf(ctx, "CookiesInit, sending GET-request to %s to get cookie...\n", loginPageUrl)
 
 	// Creating request
-	preReq, err := http.NewRequest("GET", globalvars.LoginPageUrl, nil)
+	preReq, err := http.NewRequestWithContext(inpol.WithEndpoint(ctx, inpol.EndpointLoginPage), "GET", loginPageUrl, nil)
 	if err != nil {
-		log.Fatalf("Error creating GET-request: %v", err)
+		return fmt.Errorf("CookiesInit request error creating request: %w", err)
 	}
 	// Setting headers similar to real browser
-	attachHeaders(preReq)
+	attachHeaders(preReq, client.Origin())
 	preResp, err := client.Do(preReq)
 	if err != nil {
-		return fmt.Errorf("CookiesInit request error executing: %v", err)
+		return fmt.Errorf("CookiesInit request error executing: %w", err)
 	}
 	defer preResp.Body.Close()
-	fmt.Println("✅ CookiesInit, cookies initialized successfully!")
+	output.Println(ctx, "✅ CookiesInit, cookies initialized successfully!")
 	return nil
 }
 
-func attachHeaders(req *http.Request) {
+func attachHeaders(req *http.Request, origin string) {
 	req.Header.Set("User-Agent", globalvars.DefaultUserAgent)
 	req.Header.Set("Accept", globalvars.HtmlAcceptHeader)
 	req.Header.Set("Connection", globalvars.KeepAliveHeader)
-	req.Header.Set("Accept-Encoding", globalvars.AcceptEncodingHeader)
 	req.Header.Set("Upgrade-Insecure-Requests", "1")
-	req.Header.Set("Referer", globalvars.Origin)
+	req.Header.Set("Referer", origin)
 	req.Header.Set("Sec-Fetch-Dest", "document")
 	req.Header.Set("Sec-Fetch-Mode", "navigate")
 	req.Header.Set("Sec-Fetch-Site", "none")
@@ -0,0 +1,130 @@
+package requests
+
+import (
+	modelerrors "bot-main/models/errors"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DailyBudget is a hard limit of requests an account may send per day.
+// Usage is kept in a JSON file shared by all accounts, so the budget
+// survives restarts.
+type DailyBudget struct {
+	path    string
+	account string
+	limit   int
+	now     func() time.Time
+
+	// Shared by budgets of all accounts using the same file.
+	mu *sync.Mutex
+}
+
+// budgetFileLocks serialize access to budget files, accounts running
+// concurrently keep their usage in one file.
+var budgetFileLocks sync.Map
+
+func budgetFileLock(path string) *sync.Mutex {
+	if absolutePath, err := filepath.Abs(path); err == nil {
+		path = absolutePath
+	}
+	lock, _ := budgetFileLocks.LoadOrStore(path, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+type budgetUsage struct {
+	Date string `json:"date"`
+	Used int    `json:"used"`
+}
+
+const budgetDateLayout = "2006-01-02"
+
+// NewDailyBudget creates the budget of the account, limit of zero or less
+// means there is no limit.
+func NewDailyBudget(path, account string, limit int) *DailyBudget {
+	return &DailyBudget{
+		path:    path,
+		account: strings.ToLower(account),
+		limit:   limit,
+		now:     time.Now,
+		mu:      budgetFileLock(path),
+	}
+}
+
+// Take spends one request of today's budget or returns BudgetExhaustedError
+// when nothing is left.
+func (b *DailyBudget) Take() error {
+	if b == nil || b.limit <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	usages, err := b.load()
+	if err != nil {
+		return err
+	}
+	now := b.now()
+	usage := usages[b.account]
+	if usage.Date != now.Format(budgetDateLayout) {
+		usage = budgetUsage{Date: now.Format(budgetDateLayout)}
+	}
+	if usage.Used >= b.limit {
+		year, month, day := now.Date()
+		resetAt := time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+		return modelerrors.BudgetExhaustedError{
+			Message: fmt.Sprintf("❌ Daily budget of %d requests for %s is exhausted, it resets at %s",
+				b.limit, b.account, resetAt.Format(time.DateTime)),
+			ResetAt: resetAt,
+		}
+	}
+	usage.Used++
+	usages[b.account] = usage
+	return b.save(usages)
+}
+
+func (b *DailyBudget) load() (map[string]budgetUsage, error) {
+	usages := make(map[string]budgetUsage)
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return usages, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("DailyBudget error reading %s: %w", b.path, err)
+	}
+	if err = json.Unmarshal(data, &usages); err != nil {
+		return nil, fmt.Errorf("DailyBudget %s JSON parcing error: %w", b.path, err)
+	}
+	return usages, nil
+}
+
+// save writes the usages through a temporary file,
+// so the budget isn't lost if the bot dies halfway.
+func (b *DailyBudget) save(usages map[string]budgetUsage) error {
+	data, err := json.MarshalIndent(usages, "", "  ")
+	if err != nil {
+		return fmt.Errorf("DailyBudget error encoding JSON: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("DailyBudget error saving %s: %w", b.path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("DailyBudget error saving %s: %w", b.path, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("DailyBudget error saving %s: %w", b.path, err)
+	}
+	if err = os.Rename(tmp.Name(), b.path); err != nil {
+		return fmt.Errorf("DailyBudget error saving %s: %w", b.path, err)
+	}
+	return nil
+}
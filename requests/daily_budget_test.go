@@ -0,0 +1,101 @@
+package requests
+
+import (
+	modelerrors "bot-main/models/errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDailyBudget(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "budget.json")
+	now := time.Date(2025, 10, 3, 23, 0, 0, 0, time.UTC)
+	newBudget := func(account string) *DailyBudget {
+		budget := NewDailyBudget(path, account, 2)
+		budget.now = func() time.Time { return now }
+		return budget
+	}
+
+	budget := newBudget("User@Example.com")
+	assert.NoError(t, budget.Take())
+	assert.NoError(t, budget.Take())
+
+	// Usage is persisted, a restarted bot can't spend more.
+	restarted := newBudget("user@example.com")
+	used, err := usedToday(restarted)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, used)
+	err = restarted.Take()
+	var budgetExhaustedError modelerrors.BudgetExhaustedError
+	assert.ErrorAs(t, err, &budgetExhaustedError)
+	assert.Equal(t, time.Date(2025, 10, 4, 0, 0, 0, 0, time.UTC), budgetExhaustedError.ResetAt)
+
+	// Other accounts have their own budget.
+	assert.NoError(t, newBudget("other@example.com").Take())
+
+	// Budget is renewed the next day.
+	now = now.Add(2 * time.Hour)
+	assert.NoError(t, restarted.Take())
+	used, err = usedToday(restarted)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, used)
+}
+
+func TestDailyBudgetWithoutLimit(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "budget.json")
+	budget := NewDailyBudget(path, "user@example.com", 0)
+	for range 10 {
+		assert.NoError(t, budget.Take())
+	}
+	assert.NoFileExists(t, path)
+}
+
+func TestDailyBudgetSharedFileConcurrently(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "budget.json")
+	budgets := []*DailyBudget{
+		NewDailyBudget(path, "anna@example.com", 1000),
+		NewDailyBudget(path, "jan@example.com", 1000),
+	}
+	var wg sync.WaitGroup
+	for _, budget := range budgets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				assert.NoError(t, budget.Take())
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, budget := range budgets {
+		used, err := usedToday(budget)
+		assert.NoError(t, err)
+		assert.Equal(t, 50, used)
+	}
+}
+
+// usedToday returns how many requests of the budget were sent today.
+func usedToday(b *DailyBudget) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	usages, err := b.load()
+	if err != nil {
+		return 0, err
+	}
+	usage := usages[b.account]
+	if usage.Date != b.now().Format(budgetDateLayout) {
+		return 0, nil
+	}
+	return usage.Used, nil
+}
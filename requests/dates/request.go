@@ -1,10 +1,11 @@
 package dates
 
 import (
-	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
-	"bot-main/utils"
+	"bot-main/output"
+	"bot-main/requests/inpol"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,55 +14,50 @@ import (
 )
 
 func GetReservationQueueDates(
-	client *http.Client,
-	sessionToken string,
+	ctx context.Context,
+	client *inpol.Client,
 	proceeding *models.DetailedProceedingData,
 	reservationQueue models.ReservationQueue) ([]string, error) {
 	if client == nil {
-		return nil, fmt.Errorf("GetReservationQueueDates, HTTP client is nil")
+		return nil, fmt.Errorf("GetReservationQueueDates, client is nil")
 	}
 	if proceeding == nil {
 		return nil, fmt.Errorf("GetReservationQueueDates, proceeding data is nil")
 	}
-	getReservationQueueDatesRequestUrl := fmt.Sprintf(globalvars.GetReservationQueueDatesRequestUrl, reservationQueue.ID)
-	homePageCasesUrl := fmt.Sprintf(globalvars.HomePageCasesUrl, proceeding.ID)
-	req, err := http.NewRequest("POST", getReservationQueueDatesRequestUrl, nil)
+	getReservationQueueDatesRequestUrl := client.GetReservationQueueDatesRequestUrl(reservationQueue.ID)
+	homePageCasesUrl := client.HomePageCasesUrl(proceeding.ID)
+	req, err := client.NewRequest(ctx, inpol.EndpointDates, "POST", getReservationQueueDatesRequestUrl, homePageCasesUrl, nil)
 	if err != nil {
-		return nil, fmt.Errorf("GetReservationQueueDates request error creating request: %v", err)
+		return nil, fmt.Errorf("GetReservationQueueDates request error creating request: %w", err)
 	}
-	// Set headers
-	req.Header.Set("Referer", homePageCasesUrl)
-	req.Header.Set("Authorization", "Bearer "+sessionToken)
-	utils.AttachDefaultRequestHeaders(req)
 
-	fmt.Println("Sending GetReservationQueueDates request...")
+	output.Println(ctx, "Sending GetReservationQueueDates request...")
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("GetReservationQueueDates request error executing: %v", err)
+		return nil, fmt.Errorf("GetReservationQueueDates request error executing: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, modelerrors.UnauthorizedError{
-			Message: fmt.Sprintf("❌ GetReservationQueueDates failed because of unauthorized status code: %s", resp.Status),
-		}
+		portalError := inpol.NewPortalError(inpol.EndpointDates, resp, fmt.Sprintf("❌ GetReservationQueueDates failed because of unauthorized status code: %s", resp.Status))
+		return nil, modelerrors.UnauthorizedError{Message: portalError.Message, Portal: &portalError}
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GetReservationQueueDates request failed with status: %s", resp.Status)
+		return nil, inpol.NewPortalError(inpol.EndpointDates, resp, fmt.Sprintf("GetReservationQueueDates request failed with status: %s", resp.Status))
 	}
 
-	fmt.Printf("GetReservationQueueDates response: %s\n", resp.Status)
+	output.Printf(ctx, "GetReservationQueueDates response: %s\n", resp.Status)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("GetReservationQueueDates request error reading response body: %v", err)
+		return nil, fmt.Errorf("GetReservationQueueDates request error reading response body: %w", err)
 	}
 
 	var reservationQueueDates []string
 	err = json.Unmarshal(body, &reservationQueueDates)
 	if err != nil {
-		return nil, fmt.Errorf("GetReservationQueueDates body JSON parcing error: %v", err)
+		return nil, fmt.Errorf("GetReservationQueueDates body JSON parcing error: %w", err)
 	}
 
 	for i, date := range reservationQueueDates {
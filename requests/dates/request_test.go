@@ -1,11 +1,11 @@
 package dates
 
 import (
-	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
 	test_utils "bot-main/tests/utils"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -16,8 +16,7 @@ import (
 )
 
 func TestGetReservationQueueDates(t *testing.T) {
-	globalvars.GetReservationQueueDatesRequestUrl = "https://fake/queue/%s/dates"
-	globalvars.HomePageCasesUrl = "https://fake/cases/%s"
+	t.Parallel()
 
 	sampleProceeding := models.DetailedProceedingData{
 		ID: "abc123",
@@ -41,7 +40,7 @@ func TestGetReservationQueueDates(t *testing.T) {
 		{
 			name:       "nil client",
 			client:     nil,
-			wantErrStr: "GetReservationQueueDates, HTTP client is nil",
+			wantErrStr: "GetReservationQueueDates, client is nil",
 		},
 		{
 			name:       "nil proceeding",
@@ -92,6 +91,7 @@ func TestGetReservationQueueDates(t *testing.T) {
 			proceeding:   &sampleProceeding,
 			queue:        sampleQueue,
 			wantErrStr:   "GetReservationQueueDates request failed with status: 500 Internal Server Error",
+			wantErrType:  &modelerrors.PortalError{},
 		},
 		{
 			name: "bad json",
@@ -110,7 +110,8 @@ func TestGetReservationQueueDates(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			dates, err := GetReservationQueueDates(tc.client, tc.sessionToken, tc.proceeding, tc.queue)
+			t.Parallel()
+			dates, err := GetReservationQueueDates(context.Background(), test_utils.NewInpolClient(tc.client, tc.sessionToken), tc.proceeding, tc.queue)
 
 			if tc.wantErrStr != "" {
 				assert.Error(t, err)
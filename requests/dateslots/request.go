@@ -1,10 +1,11 @@
 package dateslots
 
 import (
-	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
-	"bot-main/utils"
+	"bot-main/output"
+	"bot-main/requests/inpol"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,56 +13,51 @@ import (
 )
 
 func GetReservationQueueDateSlots(
-	client *http.Client,
-	sessionToken string,
+	ctx context.Context,
+	client *inpol.Client,
 	proceeding *models.DetailedProceedingData,
 	reservationQueue models.ReservationQueue,
 	simpleDate string) ([]models.Slot, error) {
 	if client == nil {
-		return nil, fmt.Errorf("GetReservationQueueDateSlots, HTTP client is nil")
+		return nil, fmt.Errorf("GetReservationQueueDateSlots, client is nil")
 	}
 	if proceeding == nil {
 		return nil, fmt.Errorf("GetReservationQueueDateSlots, proceeding data is nil")
 	}
-	getReservationQueueDateSlotsRequestUrl := fmt.Sprintf(globalvars.GetReservationQueueDateSlotsRequestUrl, reservationQueue.ID, simpleDate)
-	homePageCasesUrl := fmt.Sprintf(globalvars.HomePageCasesUrl, proceeding.ID)
-	req, err := http.NewRequest("POST", getReservationQueueDateSlotsRequestUrl, nil)
+	getReservationQueueDateSlotsRequestUrl := client.GetReservationQueueDateSlotsRequestUrl(reservationQueue.ID, simpleDate)
+	homePageCasesUrl := client.HomePageCasesUrl(proceeding.ID)
+	req, err := client.NewRequest(ctx, inpol.EndpointSlots, "POST", getReservationQueueDateSlotsRequestUrl, homePageCasesUrl, nil)
 	if err != nil {
-		return nil, fmt.Errorf("GetReservationQueueDateSlots request error creating request: %v", err)
+		return nil, fmt.Errorf("GetReservationQueueDateSlots request error creating request: %w", err)
 	}
-	// Set headers
-	req.Header.Set("Referer", homePageCasesUrl)
-	req.Header.Set("Authorization", "Bearer "+sessionToken)
-	utils.AttachDefaultRequestHeaders(req)
 
-	fmt.Println("Sending GetReservationQueueDateSlots request...")
+	output.Println(ctx, "Sending GetReservationQueueDateSlots request...")
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("GetReservationQueueDateSlots request error executing: %v", err)
+		return nil, fmt.Errorf("GetReservationQueueDateSlots request error executing: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, modelerrors.UnauthorizedError{
-			Message: fmt.Sprintf("❌ GetReservationQueueDateSlots failed because of unauthorized status code: %s", resp.Status),
-		}
+		portalError := inpol.NewPortalError(inpol.EndpointSlots, resp, fmt.Sprintf("❌ GetReservationQueueDateSlots failed because of unauthorized status code: %s", resp.Status))
+		return nil, modelerrors.UnauthorizedError{Message: portalError.Message, Portal: &portalError}
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GetReservationQueueDateSlots request failed with status: %s", resp.Status)
+		return nil, inpol.NewPortalError(inpol.EndpointSlots, resp, fmt.Sprintf("GetReservationQueueDateSlots request failed with status: %s", resp.Status))
 	}
 
-	fmt.Printf("GetReservationQueueDateSlots response: %s\n", resp.Status)
+	output.Printf(ctx, "GetReservationQueueDateSlots response: %s\n", resp.Status)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("GetReservationQueueDateSlots request error reading response body: %v", err)
+		return nil, fmt.Errorf("GetReservationQueueDateSlots request error reading response body: %w", err)
 	}
 
 	var reservationQueueDateSlots []models.Slot
 	err = json.Unmarshal(body, &reservationQueueDateSlots)
 	if err != nil {
-		return nil, fmt.Errorf("GetReservationQueueDateSlots body JSON parcing error: %v", err)
+		return nil, fmt.Errorf("GetReservationQueueDateSlots body JSON parcing error: %w", err)
 	}
 
 	return reservationQueueDateSlots, nil
@@ -1,11 +1,11 @@
 package dateslots
 
 import (
-	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
 	test_utils "bot-main/tests/utils"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -16,8 +16,7 @@ import (
 )
 
 func TestGetReservationQueueDateSlots(t *testing.T) {
-	globalvars.GetReservationQueueDateSlotsRequestUrl = "https://fake/queue/%s/%s/slots"
-	globalvars.HomePageCasesUrl = "https://fake/cases/%s"
+	t.Parallel()
 
 	sampleProceeding := &models.DetailedProceedingData{
 		ID: "proc123",
@@ -43,7 +42,7 @@ func TestGetReservationQueueDateSlots(t *testing.T) {
 			proceeding: sampleProceeding,
 			queue:      sampleQueue,
 			date:       "2025-08-21",
-			wantErrStr: "HTTP client is nil",
+			wantErrStr: "client is nil",
 		},
 		{
 			name:       "nil proceeding",
@@ -56,8 +55,8 @@ func TestGetReservationQueueDateSlots(t *testing.T) {
 		{
 			name: "unauthorized",
 			client: test_utils.NewTestClient(func(req *http.Request) *http.Response {
-				assert.Equal(t, "https://fake/queue/queue456/2025-08-21/slots", req.URL.String())
-				assert.Equal(t, "https://fake/cases/proc123", req.Header.Get("Referer"))
+				assert.Equal(t, "https://fake/api/reservations/queue/queue456/2025-08-21/slots", req.URL.String())
+				assert.Equal(t, "https://fake/home/cases/proc123", req.Header.Get("Referer"))
 				return &http.Response{
 					StatusCode: http.StatusUnauthorized,
 					Status:     "401 Unauthorized",
@@ -126,7 +125,8 @@ func TestGetReservationQueueDateSlots(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := GetReservationQueueDateSlots(tc.client, tc.sessionToken, tc.proceeding, tc.queue, tc.date)
+			t.Parallel()
+			result, err := GetReservationQueueDateSlots(context.Background(), test_utils.NewInpolClient(tc.client, tc.sessionToken), tc.proceeding, tc.queue, tc.date)
 
 			if tc.wantErrStr != "" {
 				assert.Error(t, err)
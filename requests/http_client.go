@@ -0,0 +1,46 @@
+package requests
+
+import (
+	"bot-main/models"
+	"bot-main/requests/inpol"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+// newHTTPClient builds the chain every portal request goes through:
+// retries -> rate limits and daily budget -> decompression -> HTTP/1.1 transport.
+func newHTTPClient(applicationData models.ApplicationData) (*http.Client, error) {
+	// Creating custom transport, disabling HTTP/2.
+	// We are cloning default transport and changing only one setting.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		NextProtos: []string{"http/1.1"},
+	}
+	// Disabling compression as we are doing it ourselves.
+	transport.DisableCompression = true
+
+	rateLimits := DefaultRateLimits()
+	for family, limit := range applicationData.RateLimits {
+		rateLimits[inpol.EndpointFamily(family)] = limit
+	}
+
+	jar, err := inpol.NewCookieJar()
+	if err != nil {
+		return nil, fmt.Errorf("RequestPipeline error creating cookie jar: %w", err)
+	}
+	return &http.Client{
+		Jar:     jar,
+		Timeout: applicationData.HTTPTimeout,
+		Transport: &RetryingTransport{
+			Transport: &RateLimitingTransport{
+				Transport: &DecompressingTransport{Transport: transport},
+				Limiters:  NewRateLimiters(rateLimits),
+				Budget: NewDailyBudget(applicationData.BudgetFile,
+					applicationData.LoginData.Email,
+					applicationData.DailyRequestBudget),
+			},
+			Policies: DefaultRetryPolicies(),
+		},
+	}, nil
+}
@@ -0,0 +1,154 @@
+package inpol
+
+import (
+	"bot-main/globalvars"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const DefaultBaseUrl = "https://inpol.mazowieckie.pl"
+
+// Client is a single session with the INPOL portal. It owns the HTTP client
+// (and so the cookie jar), the headers sent with every API request, the
+// session token and the endpoint URLs built from its base URL, so several
+// clients can live side by side in one process.
+type Client struct {
+	httpClient *http.Client
+	baseUrl    string
+	headers    http.Header
+
+	mu    sync.RWMutex
+	token string
+}
+
+func NewClient(httpClient *http.Client, baseUrl string) *Client {
+	if baseUrl == "" {
+		baseUrl = DefaultBaseUrl
+	}
+	baseUrl = strings.TrimRight(baseUrl, "/")
+	return &Client{
+		httpClient: httpClient,
+		baseUrl:    baseUrl,
+		headers:    defaultHeaders(baseUrl),
+	}
+}
+
+// Headers are attached to every request created by NewRequest,
+// they can be changed before the client is used.
+func (c *Client) Headers() http.Header {
+	return c.headers
+}
+
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// NewRequest creates a request to the API endpoint bound to ctx with
+// the client headers, the given referer and the session token (if there is
+// one) attached.
+func (c *Client) NewRequest(ctx context.Context, endpoint Endpoint, method, requestUrl, referer string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(WithEndpoint(ctx, endpoint), method, requestUrl, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.headers {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+	if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
+
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.httpClient.Do(req)
+}
+
+// Portal pages, used as referers.
+
+func (c *Client) Origin() string {
+	return c.baseUrl
+}
+
+func (c *Client) LoginPageUrl() string {
+	return c.baseUrl + "/login"
+}
+
+func (c *Client) HomePageUrl() string {
+	return c.baseUrl + "/home"
+}
+
+func (c *Client) HomePageCasesUrl(proceedingID string) string {
+	return c.url("/home/cases/%s", proceedingID)
+}
+
+// API endpoints.
+
+func (c *Client) LoginRequestUrl() string {
+	return c.baseUrl + "/identity/sign-in"
+}
+
+func (c *Client) GetActiveProceedingsRequestUrl() string {
+	return c.baseUrl + "/api/foreigner/active-proceedings"
+}
+
+func (c *Client) GetProceedingRequestUrl(proceedingID string) string {
+	return c.url("/api/proceedings/%s", proceedingID)
+}
+
+func (c *Client) GetProceedingReservationQueuesRequestUrl(proceedingID string) string {
+	return c.url("/api/proceedings/%s/reservationQueues", proceedingID)
+}
+
+func (c *Client) GetReservationQueueDatesRequestUrl(queueID string) string {
+	return c.url("/api/reservations/queue/%s/dates", queueID)
+}
+
+func (c *Client) GetReservationQueueDateSlotsRequestUrl(queueID, simpleDate string) string {
+	return c.url("/api/reservations/queue/%s/%s/slots", queueID, simpleDate)
+}
+
+func (c *Client) ReserveAppointmentRequestUrl(queueID string) string {
+	return c.url("/api/reservations/queue/%s/reserve", queueID)
+}
+
+func (c *Client) url(pathFormat string, segments ...string) string {
+	escaped := make([]any, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+	return c.baseUrl + fmt.Sprintf(pathFormat, escaped...)
+}
+
+func defaultHeaders(origin string) http.Header {
+	headers := make(http.Header)
+	headers.Set("Content-Type", globalvars.ApplicationJson)
+	headers.Set("User-Agent", globalvars.DefaultUserAgent)
+	headers.Set("Origin", origin)
+	headers.Set("Accept-Language", "en-US,en;q=0.9,ru;q=0.8,ru-RU;q=0.7")
+	headers.Set("Pragma", "no-cache")
+	headers.Set("Sec-Fetch-Dest", "empty")
+	headers.Set("Sec-Fetch-Mode", "cors")
+	headers.Set("Sec-Fetch-Site", "same-origin")
+	headers.Set("Sec-Ch-Ua", `"Not A;Brand";v="8", "Chromium";v="138", "Google Chrome";v="138"`)
+	headers.Set("Sec-Ch-Ua-Mobile", "?0")
+	headers.Set("Sec-Ch-Ua-Platform", `"Windows"`)
+	headers.Set("priority", "u=1, i")
+	return headers
+}
@@ -0,0 +1,56 @@
+package inpol
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientUrls(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(&http.Client{}, "https://fake/")
+
+	assert.Equal(t, "https://fake", client.Origin())
+	assert.Equal(t, "https://fake/login", client.LoginPageUrl())
+	assert.Equal(t, "https://fake/identity/sign-in", client.LoginRequestUrl())
+	assert.Equal(t, "https://fake/home/cases/proc-1", client.HomePageCasesUrl("proc-1"))
+	assert.Equal(t, "https://fake/api/proceedings/proc-1/reservationQueues", client.GetProceedingReservationQueuesRequestUrl("proc-1"))
+	assert.Equal(t, "https://fake/api/reservations/queue/q-1/2025-08-21/slots", client.GetReservationQueueDateSlotsRequestUrl("q-1", "2025-08-21"))
+	assert.Equal(t, "https://fake/api/reservations/queue/a%2Fb/reserve", client.ReserveAppointmentRequestUrl("a/b"))
+}
+
+func TestClientDefaultBaseUrl(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(&http.Client{}, "")
+
+	assert.Equal(t, DefaultBaseUrl+"/api/foreigner/active-proceedings", client.GetActiveProceedingsRequestUrl())
+}
+
+func TestClientNewRequest(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(&http.Client{}, "https://fake")
+	client.Headers().Set("X-Custom", "custom")
+
+	req, err := client.NewRequest(context.Background(), EndpointActiveProceedings, "GET", client.HomePageUrl(), "https://fake/referer", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://fake", req.Header.Get("Origin"))
+	assert.Equal(t, "https://fake/referer", req.Header.Get("Referer"))
+	assert.Equal(t, "custom", req.Header.Get("X-Custom"))
+	assert.Empty(t, req.Header.Get("Authorization"))
+	assert.Equal(t, EndpointActiveProceedings, RequestEndpoint(req))
+
+	client.SetToken("tok")
+	req, err = client.NewRequest(context.Background(), EndpointDates, "GET", client.HomePageUrl(), "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer tok", req.Header.Get("Authorization"))
+	assert.Empty(t, req.Header.Get("Referer"))
+
+	// Request headers are copies, changing them doesn't touch the client.
+	req.Header.Set("X-Custom", "changed")
+	assert.Equal(t, "custom", client.Headers().Get("X-Custom"))
+}
@@ -0,0 +1,59 @@
+package inpol
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+)
+
+// CookieJar is a cookie jar which content can be dropped at once, e.g. when
+// the portal stops accepting the current cookies. It's safe to reset it while
+// requests are in flight.
+type CookieJar struct {
+	mu  sync.RWMutex
+	jar *cookiejar.Jar
+}
+
+func NewCookieJar() (*CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &CookieJar{jar: jar}, nil
+}
+
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	j.jar.SetCookies(u, cookies)
+}
+
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.jar.Cookies(u)
+}
+
+// Reset replaces all cookies with an empty jar.
+func (j *CookieJar) Reset() error {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar = jar
+	return nil
+}
+
+// ResetCookies drops all cookies of the client,
+// it works only for clients using CookieJar.
+func (c *Client) ResetCookies() error {
+	jar, ok := c.httpClient.Jar.(*CookieJar)
+	if !ok {
+		return fmt.Errorf("ResetCookies, cookie jar of type %T can't be reset", c.httpClient.Jar)
+	}
+	return jar.Reset()
+}
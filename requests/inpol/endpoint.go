@@ -0,0 +1,67 @@
+package inpol
+
+import (
+	"context"
+	"net/http"
+)
+
+// Endpoint names the portal endpoint a request is sent to, transports use it
+// to apply per-endpoint behaviour (retries, rate limits).
+type Endpoint string
+
+const (
+	EndpointLoginPage         Endpoint = "login-page"
+	EndpointLogin             Endpoint = "login"
+	EndpointActiveProceedings Endpoint = "active-proceedings"
+	EndpointProceeding        Endpoint = "proceeding"
+	EndpointReservationQueues Endpoint = "reservation-queues"
+	EndpointDates             Endpoint = "dates"
+	EndpointSlots             Endpoint = "slots"
+	EndpointReserve           Endpoint = "reserve"
+)
+
+type endpointKey struct{}
+
+func WithEndpoint(ctx context.Context, endpoint Endpoint) context.Context {
+	return context.WithValue(ctx, endpointKey{}, endpoint)
+}
+
+// RequestEndpoint returns the endpoint the request was created for,
+// empty if it wasn't tagged.
+func RequestEndpoint(req *http.Request) Endpoint {
+	endpoint, _ := req.Context().Value(endpointKey{}).(Endpoint)
+	return endpoint
+}
+
+// EndpointFamily groups endpoints which share limits.
+type EndpointFamily string
+
+const (
+	FamilyLogin       EndpointFamily = "login"
+	FamilyProceedings EndpointFamily = "proceedings"
+	FamilyDates       EndpointFamily = "dates"
+	FamilySlots       EndpointFamily = "slots"
+	FamilyReserve     EndpointFamily = "reserve"
+)
+
+func (e Endpoint) Family() EndpointFamily {
+	switch e {
+	case EndpointLoginPage, EndpointLogin:
+		return FamilyLogin
+	case EndpointActiveProceedings, EndpointProceeding, EndpointReservationQueues:
+		return FamilyProceedings
+	case EndpointDates:
+		return FamilyDates
+	case EndpointSlots:
+		return FamilySlots
+	case EndpointReserve:
+		return FamilyReserve
+	}
+	return ""
+}
+
+// Idempotent tells whether repeating a request of the endpoint is harmless,
+// a reservation may be made twice.
+func (e Endpoint) Idempotent() bool {
+	return e != EndpointReserve
+}
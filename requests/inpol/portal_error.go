@@ -0,0 +1,15 @@
+package inpol
+
+import (
+	modelerrors "bot-main/models/errors"
+	"io"
+	"net/http"
+)
+
+// NewPortalError reads the beginning of the body of the failed response
+// of the endpoint and classifies it.
+func NewPortalError(endpoint Endpoint, resp *http.Response, message string) modelerrors.PortalError {
+	// Read a bit more than kept, so truncation is noticed.
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, modelerrors.MaxPortalErrorBody+1))
+	return modelerrors.NewPortalError(string(endpoint), endpoint.Idempotent(), resp.StatusCode, body, message)
+}
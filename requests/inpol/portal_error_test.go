@@ -0,0 +1,67 @@
+package inpol
+
+import (
+	modelerrors "bot-main/models/errors"
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPortalError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name              string
+		endpoint          Endpoint
+		statusCode        int
+		body              string
+		expectedKind      error
+		expectedRetryable bool
+		expectedBody      string
+	}{
+		{name: "Session expired", statusCode: http.StatusUnauthorized, expectedKind: modelerrors.ErrSessionExpired, expectedRetryable: true},
+		{name: "Rate limited", statusCode: http.StatusTooManyRequests, expectedKind: modelerrors.ErrRateLimited, expectedRetryable: true},
+		{name: "Server unavailable", statusCode: http.StatusBadGateway, body: "bad gateway", expectedKind: modelerrors.ErrServerUnavailable, expectedRetryable: true, expectedBody: "bad gateway"},
+		{name: "Maintenance", statusCode: http.StatusServiceUnavailable, body: "Trwa PRZERWA TECHNICZNA", expectedKind: modelerrors.ErrMaintenance, expectedRetryable: true, expectedBody: "Trwa PRZERWA TECHNICZNA"},
+		{name: "Reservation failed by server", endpoint: EndpointReserve, statusCode: http.StatusBadGateway, expectedKind: modelerrors.ErrServerUnavailable},
+		{name: "Slot taken", statusCode: http.StatusConflict, expectedKind: modelerrors.ErrSlotTaken},
+		{name: "Other", statusCode: http.StatusNotFound, body: "not found", expectedBody: "not found"},
+		{
+			name:         "Truncated body",
+			statusCode:   http.StatusBadRequest,
+			body:         strings.Repeat("a", modelerrors.MaxPortalErrorBody-1) + "ąb",
+			expectedBody: strings.Repeat("a", modelerrors.MaxPortalErrorBody-1) + "...",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			resp := &http.Response{StatusCode: tc.statusCode, Body: io.NopCloser(bytes.NewReader([]byte(tc.body)))}
+
+			endpoint := cmp.Or(tc.endpoint, EndpointDates)
+			portalError := NewPortalError(endpoint, resp, "request failed")
+			assert.Equal(t, string(endpoint), portalError.Endpoint)
+			assert.Equal(t, tc.statusCode, portalError.StatusCode)
+			assert.Equal(t, tc.expectedBody, portalError.Body)
+			assert.Equal(t, tc.expectedRetryable, portalError.Retryable)
+			assert.Equal(t, tc.expectedKind, portalError.Kind)
+
+			// Wrapped and carried by typed errors it's still found.
+			err := fmt.Errorf("RequestPipeline error: %w", modelerrors.UnauthorizedError{Message: "unauthorized", Portal: &portalError})
+			var found modelerrors.PortalError
+			assert.True(t, errors.As(err, &found))
+			assert.Equal(t, tc.statusCode, found.StatusCode)
+			if tc.expectedKind != nil {
+				assert.ErrorIs(t, err, tc.expectedKind)
+			}
+		})
+	}
+}
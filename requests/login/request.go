@@ -1,22 +1,23 @@
 package login
 
 import (
-	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
-	"bot-main/utils"
+	"bot-main/output"
+	"bot-main/requests/inpol"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 )
 
-func Login(client *http.Client, loginData models.LoginData) (string, error) {
+func Login(ctx context.Context, client *inpol.Client, loginData models.LoginData) (string, error) {
 	if client == nil {
-		return "", fmt.Errorf("Login, HTTP client is nil")
+		return "", fmt.Errorf("Login, client is nil")
 	}
-	loginURL := globalvars.LoginRequestUrl
+	loginURL := client.LoginRequestUrl()
 	userEmail := loginData.Email
 	userPassword := loginData.Password
 	payload := models.LoginPayload{
@@ -27,43 +28,41 @@ func Login(client *http.Client, loginData models.LoginData) (string, error) {
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("Login request error encoding JSON: %v", err)
+		return "", fmt.Errorf("Login request error encoding JSON: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", loginURL, bytes.NewBuffer(payloadBytes))
+	// Dropping the previous token, sign-in request is sent without it.
+	client.SetToken("")
+	req, err := client.NewRequest(ctx, inpol.EndpointLogin, "POST", loginURL, client.LoginPageUrl(), bytes.NewBuffer(payloadBytes))
 	if err != nil {
-		return "", fmt.Errorf("Login request error creating request: %v", err)
+		return "", fmt.Errorf("Login request error creating request: %w", err)
 	}
 
-	// Set headers
-	req.Header.Set("Referer", globalvars.LoginPageUrl)
-	utils.AttachDefaultRequestHeaders(req)
-
-	fmt.Println("Sending login request...")
+	output.Println(ctx, "Sending login request...")
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("Login request error executing: %v", err)
+		return "", fmt.Errorf("Login request error executing: %w", err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK && !(http.StatusBadRequest <= resp.StatusCode && resp.StatusCode < 500) {
-		return "", fmt.Errorf("Login request failed with status: %s", resp.Status)
+		return "", inpol.NewPortalError(inpol.EndpointLogin, resp, fmt.Sprintf("Login request failed with status: %s", resp.Status))
 	}
 
-	fmt.Printf("Login response: %s\n", resp.Status)
+	output.Printf(ctx, "Login response: %s\n", resp.Status)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("Login request error reading response body: %v", err)
+		return "", fmt.Errorf("Login request error reading response body: %w", err)
 	}
 
 	var loginResp models.LoginResponse
 	err = json.Unmarshal(body, &loginResp)
 	if err != nil {
-		return "", fmt.Errorf("Login body JSON parcing error: %v", err)
+		return "", fmt.Errorf("Login body JSON parcing error: %w", err)
 	}
 
 	if loginResp.IsAuthSuccessful {
-		fmt.Printf("✅ Successful login!\n")
+		output.Printf(ctx, "✅ Successful login!\n")
 	} else {
 		if loginResp.Code != nil {
 			return "", modelerrors.InvalidCredentailsError{
@@ -73,5 +72,6 @@ func Login(client *http.Client, loginData models.LoginData) (string, error) {
 		return "", fmt.Errorf("❌ Login failed: %v", loginResp.ErrorMessage)
 	}
 
+	client.SetToken(loginResp.Token)
 	return loginResp.Token, nil
 }
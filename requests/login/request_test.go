@@ -1,11 +1,11 @@
 package login
 
 import (
-	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
 	test_utils "bot-main/tests/utils"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -16,8 +16,7 @@ import (
 )
 
 func TestLogin(t *testing.T) {
-	globalvars.LoginRequestUrl = "https://fake/login"
-	globalvars.LoginPageUrl = "https://fake/login/page"
+	t.Parallel()
 
 	testCases := []struct {
 		name        string
@@ -33,7 +32,7 @@ func TestLogin(t *testing.T) {
 			name:       "nil client",
 			client:     nil,
 			loginData:  models.LoginData{},
-			wantErrStr: "Login, HTTP client is nil",
+			wantErrStr: "Login, client is nil",
 		},
 		{
 			name: "successful login",
@@ -98,7 +97,8 @@ func TestLogin(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			token, err := Login(tc.client, tc.loginData)
+			t.Parallel()
+			token, err := Login(context.Background(), test_utils.NewInpolClient(tc.client, ""), tc.loginData)
 
 			if tc.wantErrStr != "" {
 				assert.Error(t, err)
@@ -1,60 +1,55 @@
 package proceeding
 
 import (
-	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
-	"bot-main/utils"
+	"bot-main/output"
+	"bot-main/requests/inpol"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 )
 
-func GetProceedingData(client *http.Client,
-	sessionToken string,
+func GetProceedingData(ctx context.Context, client *inpol.Client,
 	proceeding models.ActiveProceeding,
 ) (*models.DetailedProceedingData, error) {
 	if client == nil {
-		return nil, fmt.Errorf("GetProceedingData, HTTP client is nil")
+		return nil, fmt.Errorf("GetProceedingData, client is nil")
 	}
-	getProceedingRequestUrl := fmt.Sprintf(globalvars.GetProceedingRequestUrl, proceeding.ProceedingsID)
-	req, err := http.NewRequest("GET", getProceedingRequestUrl, nil)
+	getProceedingRequestUrl := client.GetProceedingRequestUrl(proceeding.ProceedingsID)
+	req, err := client.NewRequest(ctx, inpol.EndpointProceeding, "GET", getProceedingRequestUrl, client.HomePageUrl(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("GetProceedingData request error creating request: %v", err)
+		return nil, fmt.Errorf("GetProceedingData request error creating request: %w", err)
 	}
-	// Set headers
-	req.Header.Set("Referer", globalvars.HomePageUrl)
-	req.Header.Set("Authorization", "Bearer "+sessionToken)
-	utils.AttachDefaultRequestHeaders(req)
 
-	fmt.Println("Sending GetProceedingData request...")
+	output.Println(ctx, "Sending GetProceedingData request...")
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("GetProceedingData request error executing: %v", err)
+		return nil, fmt.Errorf("GetProceedingData request error executing: %w", err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, modelerrors.UnauthorizedError{
-			Message: fmt.Sprintf("❌ GetProceedingData failed because of unauthorized status code: %s", resp.Status),
-		}
+		portalError := inpol.NewPortalError(inpol.EndpointProceeding, resp, fmt.Sprintf("❌ GetProceedingData failed because of unauthorized status code: %s", resp.Status))
+		return nil, modelerrors.UnauthorizedError{Message: portalError.Message, Portal: &portalError}
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GetProceedingData request failed with status: %s", resp.Status)
+		return nil, inpol.NewPortalError(inpol.EndpointProceeding, resp, fmt.Sprintf("GetProceedingData request failed with status: %s", resp.Status))
 	}
 
-	fmt.Printf("GetProceedingData response: %s\n", resp.Status)
+	output.Printf(ctx, "GetProceedingData response: %s\n", resp.Status)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("GetProceedingData request error reading response body: %v", err)
+		return nil, fmt.Errorf("GetProceedingData request error reading response body: %w", err)
 	}
 
 	var proceedingData models.DetailedProceedingData
 	err = json.Unmarshal(body, &proceedingData)
 	if err != nil {
-		return nil, fmt.Errorf("GetProceedingData body JSON parcing error: %v", err)
+		return nil, fmt.Errorf("GetProceedingData body JSON parcing error: %w", err)
 	}
 
 	return &proceedingData, nil
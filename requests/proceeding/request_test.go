@@ -1,11 +1,11 @@
 package proceeding
 
 import (
-	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
 	test_utils "bot-main/tests/utils"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -16,8 +16,7 @@ import (
 )
 
 func TestGetProceedingData(t *testing.T) {
-	globalvars.GetProceedingRequestUrl = "https://fake/proceeding/%s"
-	globalvars.HomePageUrl = "https://fake/home"
+	t.Parallel()
 
 	sampleProceeding := models.ActiveProceeding{
 		ProceedingsID:     "abc123",
@@ -37,12 +36,12 @@ func TestGetProceedingData(t *testing.T) {
 			name:       "nil client",
 			client:     nil,
 			proceeding: sampleProceeding,
-			wantErrStr: "HTTP client is nil",
+			wantErrStr: "client is nil",
 		},
 		{
 			name: "unauthorized",
 			client: test_utils.NewTestClient(func(req *http.Request) *http.Response {
-				assert.Equal(t, "https://fake/proceeding/abc123", req.URL.String())
+				assert.Equal(t, "https://fake/api/proceedings/abc123", req.URL.String())
 				return &http.Response{
 					StatusCode: http.StatusUnauthorized,
 					Status:     "401 Unauthorized",
@@ -105,7 +104,8 @@ func TestGetProceedingData(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := GetProceedingData(tc.client, tc.sessionToken, tc.proceeding)
+			t.Parallel()
+			result, err := GetProceedingData(context.Background(), test_utils.NewInpolClient(tc.client, tc.sessionToken), tc.proceeding)
 
 			if tc.wantErrStr != "" {
 				assert.Error(t, err)
@@ -1,164 +1,229 @@
 package requests
 
 import (
+	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
+	"bot-main/notify"
+	"bot-main/output"
 	"bot-main/requests/activeproceedings"
-	"bot-main/requests/cookiesinit"
-	"bot-main/requests/login"
+	"bot-main/requests/inpol"
 	"bot-main/requests/proceeding"
 	"bot-main/requests/reservationqueues"
-	"bot-main/requests/reserve"
-	"crypto/tls"
+	"bot-main/selection"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math/rand"
-	"net/http"
-	"net/http/cookiejar"
+	"strings"
 	"time"
 )
 
-func RequestPipeline(applicationData models.ApplicationData) error {
-	// Creating custom transport, disabling HTTP/2.
-	// We are cloning default transport and changing only one setting.
-	// TODO: Consider using a custom transport to be able to auto uncompress gzip responses.
-	transport := http.DefaultTransport.(*http.Transport).Clone()
-	transport.TLSClientConfig = &tls.Config{
-		NextProtos: []string{"http/1.1"},
-	}
-	// Disabling compression as we are doing it ourselves.
-	transport.DisableCompression = true
-
-	jar, err := cookiejar.New(nil)
+// RequestPipeline logs in, looks up the proceeding and its queues and then
+// watches for a free slot until it's reserved or ctx is cancelled. Progress
+// is recorded into summary so it can be reported however the pipeline ends.
+func RequestPipeline(ctx context.Context, applicationData models.ApplicationData, summary *Summary) (err error) {
+	httpClient, err := newHTTPClient(applicationData)
 	if err != nil {
-		return fmt.Errorf("RequestPipeline error creating cookie jar: %v", err)
+		return err
 	}
-	client := &http.Client{
-		Jar:       jar,
-		Transport: &DecompressingTransport{Transport: transport},
+	baseUrl := applicationData.BaseUrl
+	if baseUrl == "" {
+		baseUrl = inpol.DefaultBaseUrl
 	}
+	client := inpol.NewClient(httpClient, baseUrl)
+
+	s := newSession(client, applicationData)
+	notifier := notify.New(applicationData.LoginData.Email, applicationData.Notifications)
+	defer func() {
+		var unconfirmedError modelerrors.UnconfirmedReservationError
+		// Unconfirmed reservations were notified about already.
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.As(err, &unconfirmedError) {
+			notifier.Notify(context.WithoutCancel(ctx), notify.EventPipelineFailed, err.Error())
+		}
+	}()
 
-	fmt.Println()
-	fmt.Println("RequestPipeline started, initializing cookies...")
-	err = cookiesinit.CookiesInit(client)
+	output.Println(ctx)
+	output.Println(ctx, "RequestPipeline started.")
+	err = s.login(ctx)
 	if err != nil {
-		fmt.Printf("RequestPipeline error during initializing cookies: %v", err)
+		output.Println(ctx, err)
 		return err
 	}
+	summary.stepDone("Logged in as %s", applicationData.LoginData.Email)
 
 	//////////////////////////////////////////////////////
-	time.Sleep(time.Duration(rand.Float32()) * time.Second)
-
-	fmt.Println()
-	fmt.Println("RequestPipeline, trying to login...")
-	sessionToken, err := login.Login(client, applicationData.LoginData)
-	if err != nil {
-		fmt.Printf("RequestPipeline error during login: %v", err)
+	if err = sleep(ctx, randomPause()); err != nil {
 		return err
 	}
-	fmt.Printf("Login request completed successfully, token: %s.\n", sessionToken)
 
-	//////////////////////////////////////////////////////
-	time.Sleep(time.Duration(rand.Float32()) * time.Second)
-
-	fmt.Println()
-	fmt.Println("RequestPipeline, trying to get active proceedings...")
-	activeProceedings, err := activeproceedings.GetActiveProceedings(client, sessionToken)
+	output.Println(ctx)
+	output.Println(ctx, "RequestPipeline, trying to get active proceedings...")
+	var activeProceedings []models.ActiveProceeding
+	err = s.call(ctx, "getting active proceedings", func() (err error) {
+		activeProceedings, err = activeproceedings.GetActiveProceedings(ctx, client)
+		return err
+	})
 	if err != nil {
-		fmt.Printf("RequestPipeline error during getting active proceedings: %v", err)
+		output.Printf(ctx, "RequestPipeline error during getting active proceedings: %v", err)
 		return err
 	}
-	fmt.Println("Get active proceedings request completed successfully, proceedings:")
-	printData(activeProceedings)
-	if len(activeProceedings) <= applicationData.ProceedingsCheckIndex {
-		fmt.Println("RequestPipeline, proceedings length and index incompatibility, returning error.")
+	output.Println(ctx, "Get active proceedings request completed successfully, proceedings:")
+	printData(ctx, activeProceedings)
+	selectedProceedings, err := selection.SelectProceedings(activeProceedings, applicationData.ProceedingSelector, applicationData.ProceedingsCheckIndex)
+	if err != nil {
+		output.Println(ctx, "RequestPipeline, no active proceeding was selected, returning error.")
 		return modelerrors.ProceedingsCountError{
-			Message: fmt.Sprintf("❌ RequestPipeline failed because proceedings count and index incompatibility: %d and %d.",
-				len(activeProceedings),
-				applicationData.ProceedingsCheckIndex),
+			Message: fmt.Sprintf("❌ RequestPipeline failed to select proceedings: %v", err),
 		}
 	}
+	summary.stepDone("Found %d active proceeding(s), %d selected", len(activeProceedings), len(selectedProceedings))
+
+	var targets []watchTarget
+	var skipReasons []string
+	for _, relevantProceeding := range selectedProceedings {
+		target, skipReason, err := prepareWatchTarget(ctx, s, summary, applicationData, relevantProceeding)
+		if err != nil {
+			return err
+		}
+		if skipReason != "" {
+			output.Printf(ctx, "RequestPipeline, skipping proceeding %s: %s.\n", relevantProceeding.ProceedingsID, skipReason)
+			skipReasons = append(skipReasons, fmt.Sprintf("%s: %s", relevantProceeding.ProceedingsID, skipReason))
+			continue
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("❌ RequestPipeline failed because no selected proceeding can be watched (%s)", strings.Join(skipReasons, "; "))
+	}
 
 	//////////////////////////////////////////////////////
-	time.Sleep(time.Duration(rand.Float32()) * time.Second)
-	relevantProceeding := activeProceedings[applicationData.ProceedingsCheckIndex]
+	if err = sleep(ctx, randomPause()); err != nil {
+		return err
+	}
 
-	fmt.Println()
-	fmt.Printf("RequestPipeline, trying to get detailed info about proceeding %s...\n", relevantProceeding.ProceedingsID)
-	proceedingData, err := proceeding.GetProceedingData(client, sessionToken, relevantProceeding)
+	selector, err := selection.NewSlotSelector(applicationData.SlotStrategy)
 	if err != nil {
-		fmt.Printf("RequestPipeline error during getting detailed proceeding data: %v", err)
 		return err
 	}
-	fmt.Printf("Get detailed proceeding data for %s completed successfully, data:\n", relevantProceeding.ProceedingsID)
-	printData(proceedingData)
+	output.Println(ctx)
+	output.Printf(ctx, "RequestPipeline, watching %d proceeding(s) for free slots every %s...\n", len(targets), applicationData.WatchInterval)
+	w := &watcher{
+		session:         s,
+		summary:         summary,
+		notifier:        notifier,
+		applicationData: applicationData,
+		targets:         targets,
+		selector:        selector,
+		verifyWindow:    reservationVerifyWindow,
+	}
+	reservedSlots, err := w.watchAndReserve(ctx)
+	if err != nil {
+		output.Printf(ctx, "RequestPipeline error during watching for date slots: %v", err)
+		return err
+	}
+	for _, reservedSlot := range reservedSlots {
+		if applicationData.DryRun {
+			output.Printf(ctx, "Dry run finished, date slot for %s would be reserved.\n", reservedSlot.Date)
+			continue
+		}
+		output.Printf(ctx, "Reserving date slot for %s completed successfully!\n", reservedSlot.Date)
+	}
+
+	return nil
+}
 
+// prepareWatchTarget gets details and reservation queues of the proceeding.
+// A proceeding which can't be watched gives a skip reason instead of an error,
+// so other selected proceedings are still watched.
+func prepareWatchTarget(
+	ctx context.Context,
+	s *session,
+	summary *Summary,
+	applicationData models.ApplicationData,
+	relevantProceeding models.ActiveProceeding) (watchTarget, string, error) {
 	//////////////////////////////////////////////////////
-	time.Sleep(time.Duration(rand.Float32()) * time.Second)
+	if err := sleep(ctx, randomPause()); err != nil {
+		return watchTarget{}, "", err
+	}
 
-	fmt.Println()
-	fmt.Printf("RequestPipeline, trying to get queues for reservation for proceeding %s...\n", proceedingData.ID)
-	reservationQueues, err := reservationqueues.GetReservationQueues(client, sessionToken, proceedingData)
-	if err != nil {
-		fmt.Printf("RequestPipeline error during getting reservation queues: %v", err)
+	output.Println(ctx)
+	output.Printf(ctx, "RequestPipeline, trying to get detailed info about proceeding %s...\n", relevantProceeding.ProceedingsID)
+	var proceedingData *models.DetailedProceedingData
+	err := s.call(ctx, "getting detailed proceeding data", func() (err error) {
+		proceedingData, err = proceeding.GetProceedingData(ctx, s.client, relevantProceeding)
 		return err
+	})
+	if err != nil {
+		output.Printf(ctx, "RequestPipeline error during getting detailed proceeding data: %v", err)
+		return watchTarget{}, "", err
+	}
+	output.Printf(ctx, "Get detailed proceeding data for %s completed successfully, data:\n", relevantProceeding.ProceedingsID)
+	printData(ctx, proceedingData)
+	summary.stepDone("Got details of proceeding %s", proceedingData.ID)
+
+	if !proceedingData.CanMakeAppointment {
+		if applicationData.ProceedingSelector.Kind == models.SelectAllProceedings {
+			return watchTarget{}, "appointments can't be made for it", nil
+		}
+		output.Printf(ctx, "⚠️ RequestPipeline, portal says appointments can't be made for proceeding %s, watching it anyway as it was selected explicitly.\n", proceedingData.ID)
+	}
+
+	var current, bookBefore time.Time
+	appointment, found := madeAppointment(proceedingData)
+	if applicationData.Reschedule {
+		current = currentAppointment(ctx, applicationData, proceedingData.ID, appointment, found)
+	}
+	if !current.IsZero() {
+		bookBefore = current.Add(-applicationData.RescheduleMargin)
+		output.Printf(ctx, "RequestPipeline, rescheduling proceeding %s: current appointment %s, looking for slots before %s.\n", proceedingData.ID,
+			current.In(selection.Warsaw).Format(selection.AppointmentLayout), bookBefore.In(selection.Warsaw).Format(selection.AppointmentLayout))
+	} else if applicationData.Reschedule {
+		output.Printf(ctx, "RequestPipeline, proceeding %s has no appointment to reschedule, any slot will be booked.\n", proceedingData.ID)
+	} else if found {
+		if !applicationData.AllowDoubleBooking {
+			output.Printf(ctx, "%v.\n", appointmentExistsError(proceedingData.ID, appointment))
+			return watchTarget{}, fmt.Sprintf("it already has an appointment (%s event dated %s)",
+				globalvars.AppointmentMade, appointment.Date.Format(time.DateTime)), nil
+		}
+		output.Printf(ctx, "⚠️ RequestPipeline, proceeding %s already has an appointment (%s event dated %s), booking another one as double booking is allowed.\n",
+			proceedingData.ID, globalvars.AppointmentMade, appointment.Date.Format(time.DateTime))
 	}
-	fmt.Printf("Get reservation queues for %s completed successfully, queues:\n", relevantProceeding.ProceedingsID)
-	printData(reservationQueues)
 
 	//////////////////////////////////////////////////////
-	time.Sleep(time.Duration(rand.Float32()) * time.Second)
-
-	relevantQueue := reservationQueues[0]
-	// fmt.Println()
-	// fmt.Printf("RequestPipeline, trying to get dates for query %s...\n", relevantQueue.Localization)
-	// queueDates, err := dates.GetReservationQueueDates(client, sessionToken, proceedingData, relevantQueue)
-	// if err != nil {
-	// 	fmt.Printf("RequestPipeline error during getting queue dates: %v", err)
-	// 	return err
-	// }
-	// fmt.Printf("Get queue dates for %s completed successfully, dates:\n", relevantQueue.ID)
-	// printData(queueDates)
-
-	// //////////////////////////////////////////////////////
-	// time.Sleep(time.Duration(rand.Float32()) * time.Second)
-
-	// queueDate := queueDates[len(queueDates)-1]
-	// fmt.Println()
-	// fmt.Printf("RequestPipeline, trying to get date slots for date %s at %s...\n", queueDate, relevantQueue.Localization)
-	// queueDateSlots, err := dateslots.GetReservationQueueDateSlots(client, sessionToken, proceedingData, relevantQueue, queueDate)
-	// if err != nil {
-	// 	fmt.Printf("RequestPipeline error during getting queue date slots: %v", err)
-	// 	return err
-	// }
-	// fmt.Printf("Get queue date slots for %s completed successfully, date slots:\n", relevantQueue.Localization)
-	// printData(queueDateSlots)
-
-	// //////////////////////////////////////////////////////
-	// time.Sleep(time.Duration(rand.Float32()) * time.Second)
-
-	mockSlot := models.Slot{
-		ID:    23499339,
-		Count: 1,
-		Date:  "2025-10-03T14:25:00",
-	}
-	fmt.Println()
-	fmt.Printf("RequestPipeline, trying to reserve date slot %s at %s...\n", mockSlot.Date, relevantQueue.Localization)
-	err = reserve.ReserveDateSlot(client, sessionToken, proceedingData, relevantQueue, mockSlot)
-	if err != nil {
-		fmt.Printf("RequestPipeline error during reserving date slot: %v", err)
+	if err = sleep(ctx, randomPause()); err != nil {
+		return watchTarget{}, "", err
+	}
+
+	output.Println(ctx)
+	output.Printf(ctx, "RequestPipeline, trying to get queues for reservation for proceeding %s...\n", proceedingData.ID)
+	var reservationQueues []models.ReservationQueue
+	err = s.call(ctx, "getting reservation queues", func() (err error) {
+		reservationQueues, err = reservationqueues.GetReservationQueues(ctx, s.client, proceedingData)
 		return err
+	})
+	if err != nil {
+		output.Printf(ctx, "RequestPipeline error during getting reservation queues: %v", err)
+		return watchTarget{}, "", err
 	}
-	fmt.Printf("Reserving date slot for %s for %s completed successfully!\n", relevantQueue.Localization, mockSlot.Date)
+	output.Printf(ctx, "Get reservation queues for %s completed successfully, queues:\n", relevantProceeding.ProceedingsID)
+	printData(ctx, reservationQueues)
+	summary.stepDone("Got %d reservation queue(s) of proceeding %s", len(reservationQueues), proceedingData.ID)
 
-	return nil
+	queues, err := selection.SelectQueues(reservationQueues, applicationData.QueuePreferences, applicationData.QueueIndex)
+	if err != nil {
+		output.Printf(ctx, "❌ RequestPipeline, no reservation queue of proceeding %s can be watched: %v\n", proceedingData.ID, err)
+		return watchTarget{}, err.Error(), nil
+	}
+	output.Printf(ctx, "RequestPipeline, proceeding %s will be watched at: %s\n", proceedingData.ID, selection.DescribeQueues(queues))
+	return watchTarget{proceedingData: proceedingData, queues: queues, currentAppointment: current, bookBefore: bookBefore}, "", nil
 }
 
-func printData(input any) {
+func printData(ctx context.Context, input any) {
 	data, err := json.MarshalIndent(input, "", "  ")
 	if err != nil {
-		fmt.Println("Error:", err)
+		output.Println(ctx, "Error:", err)
 		return
 	}
-	fmt.Println(string(data))
+	output.Println(ctx, string(data))
 }
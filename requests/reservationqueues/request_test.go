@@ -1,11 +1,11 @@
 package reservationqueues
 
 import (
-	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
 	test_utils "bot-main/tests/utils"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -16,8 +16,7 @@ import (
 )
 
 func TestGetReservationQueues(t *testing.T) {
-	globalvars.GetProceedingReservationQueuesRequestUrl = "https://fake/proceedings/%s/queues"
-	globalvars.HomePageCasesUrl = "https://fake/cases/%s"
+	t.Parallel()
 
 	sampleProceeding := models.DetailedProceedingData{
 		ID: "12345",
@@ -35,7 +34,7 @@ func TestGetReservationQueues(t *testing.T) {
 		{
 			name:       "nil client",
 			client:     nil,
-			wantErrStr: "GetReservationQueues, HTTP client is nil",
+			wantErrStr: "GetReservationQueues, client is nil",
 		},
 		{
 			name:       "nil proceeding",
@@ -107,7 +106,8 @@ func TestGetReservationQueues(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			queues, err := GetReservationQueues(tc.client, tc.sessionToken, tc.proceeding)
+			t.Parallel()
+			queues, err := GetReservationQueues(context.Background(), test_utils.NewInpolClient(tc.client, tc.sessionToken), tc.proceeding)
 
 			if tc.wantErrStr != "" {
 				assert.Error(t, err)
@@ -1,72 +1,147 @@
 package reserve
 
 import (
-	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
-	"bot-main/utils"
+	"bot-main/output"
+	"bot-main/requests/inpol"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"strings"
 )
 
+// NewReservePayload is the body of the reservation request for the slot.
+func NewReservePayload(proceeding *models.DetailedProceedingData, dateSlot models.Slot) models.ReservePayload {
+	return models.ReservePayload{
+		ProceedingID: proceeding.ID,
+		SlotID:       int64(dateSlot.ID),
+		Name:         proceeding.Person.FirstName,
+		LastName:     proceeding.Person.Surname,
+		DateOfBirth:  proceeding.Person.DateOfBirth,
+	}
+}
+
+// Codes of rejected reservations. The portal doesn't document them, they
+// are matched case insensitively and the status code is used otherwise.
+var (
+	slotTakenCodes             = []string{"SlotTaken", "SlotNotAvailable", "SlotAlreadyReserved"}
+	invalidPersonDataCodes     = []string{"InvalidPersonData", "InvalidName", "InvalidLastName", "InvalidDateOfBirth"}
+	proceedingNotEligibleCodes = []string{"ProceedingNotEligible", "CannotMakeAppointment", "AppointmentAlreadyMade"}
+)
+
+// ReserveDateSlot reserves the slot and returns what the portal replied
+// about the reservation. A reply which can't be decoded doesn't fail
+// the reservation, the result is empty then.
 func ReserveDateSlot(
-	client *http.Client,
-	sessionToken string,
+	ctx context.Context,
+	client *inpol.Client,
 	proceeding *models.DetailedProceedingData,
 	reservationQueue models.ReservationQueue,
-	dateSlot models.Slot) error {
+	dateSlot models.Slot) (models.ReservationResult, error) {
+	var result models.ReservationResult
 	if client == nil {
-		return fmt.Errorf("ReserveDateSlot, HTTP client is nil")
+		return result, fmt.Errorf("ReserveDateSlot, client is nil")
 	}
 	if proceeding == nil {
-		return fmt.Errorf("ReserveDateSlot, proceeding data is nil")
-	}
-	payload := models.ReservePayload{
-		ProceedingID: proceeding.ID,
-		SlotID:       int64(dateSlot.ID),
-		Name:         proceeding.Person.FirstName,
-		LastName:     proceeding.Person.Surname,
-		DateOfBirth:  proceeding.Person.DateOfBirth,
+		return result, fmt.Errorf("ReserveDateSlot, proceeding data is nil")
 	}
-	payloadBytes, err := json.Marshal(payload)
+	payloadBytes, err := json.Marshal(NewReservePayload(proceeding, dateSlot))
 	if err != nil {
-		return fmt.Errorf("ReserveDateSlot request error encoding JSON: %v", err)
+		return result, fmt.Errorf("ReserveDateSlot request error encoding JSON: %w", err)
 	}
-	reserveAppointmentRequestUrl := fmt.Sprintf(globalvars.ReserveAppointmentRequestUrl, reservationQueue.ID)
-	homePageCasesUrl := fmt.Sprintf(globalvars.HomePageCasesUrl, proceeding.ID)
-	req, err := http.NewRequest("POST", reserveAppointmentRequestUrl, bytes.NewBuffer(payloadBytes))
+	reserveAppointmentRequestUrl := client.ReserveAppointmentRequestUrl(reservationQueue.ID)
+	homePageCasesUrl := client.HomePageCasesUrl(proceeding.ID)
+	req, err := client.NewRequest(ctx, inpol.EndpointReserve, "POST", reserveAppointmentRequestUrl, homePageCasesUrl, bytes.NewBuffer(payloadBytes))
 	if err != nil {
-		return fmt.Errorf("ReserveDateSlot request error creating request: %v", err)
+		return result, fmt.Errorf("ReserveDateSlot request error creating request: %w", err)
 	}
-	// Set headers
-	req.Header.Set("Referer", homePageCasesUrl)
-	req.Header.Set("Authorization", "Bearer "+sessionToken)
-	utils.AttachDefaultRequestHeaders(req)
 
-	fmt.Println("Sending ReserveDateSlot request...")
+	output.Println(ctx, "Sending ReserveDateSlot request...")
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("ReserveDateSlot request error executing: %v", err)
+		return result, fmt.Errorf("ReserveDateSlot request error executing: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusUnauthorized {
-		return modelerrors.UnauthorizedError{
-			Message: fmt.Sprintf("❌ ReserveDateSlot failed because of unauthorized status code: %s", resp.Status),
-		}
+		portalError := inpol.NewPortalError(inpol.EndpointReserve, resp, fmt.Sprintf("❌ ReserveDateSlot failed because of unauthorized status code: %s", resp.Status))
+		return result, modelerrors.UnauthorizedError{Message: portalError.Message, Portal: &portalError}
 	} else if resp.StatusCode == http.StatusForbidden {
-		return modelerrors.ForbiddenError{
-			Message: fmt.Sprintf("❌ ReserveDateSlot failed because of forbidden status code: %s, probably needs cookies update", resp.Status),
-		}
+		portalError := inpol.NewPortalError(inpol.EndpointReserve, resp,
+			fmt.Sprintf("❌ ReserveDateSlot failed because of forbidden status code: %s, probably needs cookies update", resp.Status))
+		return result, modelerrors.ForbiddenError{Message: portalError.Message, Portal: &portalError}
 	}
 
+	body, err := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("ReserveDateSlot request for %s failed with status: %s", dateSlot.Date, resp.Status)
+		return result, rejectionError(resp, body, dateSlot)
+	}
+	output.Printf(ctx, "ReserveDateSlot %s response: %s\n", dateSlot.Date, resp.Status)
+	if err != nil {
+		// The slot is reserved already, only its details are lost.
+		output.Printf(ctx, "⚠️ ReserveDateSlot error reading response body: %v\n", err)
+		return result, nil
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		output.Printf(ctx, "⚠️ ReserveDateSlot response body isn't a reservation: %v, body: %s\n", err, body)
+		return models.ReservationResult{}, nil
 	}
+	return result, nil
+}
 
-	fmt.Printf("ReserveDateSlot %s response: %s\n", dateSlot.Date, resp.Status)
+// rejectionError maps the reply to a rejected reservation to a typed error,
+// by the code in its body when it's a known one and by the status code otherwise.
+// 409 is assumed to be the answer for a slot booked by somebody else, the portal
+// reply hasn't been confirmed yet.
+func rejectionError(resp *http.Response, body []byte, dateSlot models.Slot) error {
+	var rejection models.ReservationErrorResponse
+	// Bodies which aren't JSON leave the code empty.
+	_ = json.Unmarshal(body, &rejection)
+	reason := resp.Status
+	if rejection.Message != "" {
+		reason = fmt.Sprintf("%s, %s", resp.Status, rejection.Message)
+	}
+	hasCode := func(codes []string) bool {
+		return slices.ContainsFunc(codes, func(code string) bool { return strings.EqualFold(code, rejection.Code) })
+	}
 
-	return nil
+	portalError := modelerrors.NewPortalError(string(inpol.EndpointReserve), inpol.EndpointReserve.Idempotent(), resp.StatusCode, body,
+		fmt.Sprintf("ReserveDateSlot request for %s failed with status: %s", dateSlot.Date, reason))
+	slotTaken := func() error {
+		portalError.Kind = modelerrors.ErrSlotTaken
+		return modelerrors.SlotTakenError{
+			Message: fmt.Sprintf("❌ ReserveDateSlot failed because slot %s is already taken: %s", dateSlot.Date, reason),
+			Portal:  &portalError,
+		}
+	}
+	invalidPersonData := func() error {
+		portalError.Kind = nil
+		return modelerrors.InvalidPersonDataError{
+			Message: fmt.Sprintf("❌ ReserveDateSlot failed because person data of the proceeding was rejected: %s", reason),
+			Portal:  &portalError,
+		}
+	}
+	switch {
+	case hasCode(slotTakenCodes):
+		return slotTaken()
+	case hasCode(invalidPersonDataCodes):
+		return invalidPersonData()
+	case hasCode(proceedingNotEligibleCodes):
+		// Not a taken slot, whatever the status says.
+		portalError.Kind = nil
+		return modelerrors.ProceedingNotEligibleError{
+			Message: fmt.Sprintf("❌ ReserveDateSlot failed because the proceeding can't get an appointment: %s", reason),
+			Portal:  &portalError,
+		}
+	case resp.StatusCode == http.StatusConflict:
+		return slotTaken()
+	case resp.StatusCode == http.StatusUnprocessableEntity:
+		return invalidPersonData()
+	}
+	return portalError
 }
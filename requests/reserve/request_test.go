@@ -1,11 +1,11 @@
 package reserve
 
 import (
-	"bot-main/globalvars"
 	"bot-main/models"
 	modelerrors "bot-main/models/errors"
 	test_utils "bot-main/tests/utils"
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"net/http"
@@ -37,8 +37,7 @@ func sampleQueue() models.ReservationQueue {
 }
 
 func TestReserveDateSlot(t *testing.T) {
-	globalvars.ReserveAppointmentRequestUrl = "https://fake/reservation/%s"
-	globalvars.HomePageCasesUrl = "https://fake/cases/%s"
+	t.Parallel()
 
 	testCases := []struct {
 		name        string
@@ -47,8 +46,11 @@ func TestReserveDateSlot(t *testing.T) {
 		proceeding  *models.DetailedProceedingData
 		queue       models.ReservationQueue
 		slot        models.Slot
+		wantResult  models.ReservationResult
 		wantErrStr  string
 		wantErrType any
+		// Kind of the portal error, checked when set.
+		wantKind error
 	}{
 		{
 			name:       "nil client",
@@ -57,7 +59,7 @@ func TestReserveDateSlot(t *testing.T) {
 			proceeding: sampleProceeding(),
 			queue:      sampleQueue(),
 			slot:       sampleSlot(),
-			wantErrStr: "HTTP client is nil",
+			wantErrStr: "client is nil",
 		},
 		{
 			name:       "nil proceeding",
@@ -78,7 +80,7 @@ func TestReserveDateSlot(t *testing.T) {
 				if req.Header.Get("Authorization") != "Bearer tok" {
 					t.Errorf("expected Authorization header")
 				}
-				resp := `{"ok":true}`
+				resp := `{"id":"RES-77","proceedingId":"proc-123","slotId":42,"date":"2025-10-03T11:30:00","localization":"Okopowa 7","queueId":"queue-1"}`
 				return &http.Response{
 					StatusCode: http.StatusOK,
 					Body:       io.NopCloser(bytes.NewReader([]byte(resp))),
@@ -89,6 +91,27 @@ func TestReserveDateSlot(t *testing.T) {
 			proceeding: sampleProceeding(),
 			queue:      sampleQueue(),
 			slot:       sampleSlot(),
+			wantResult: models.ReservationResult{
+				ConfirmationID: "RES-77",
+				ProceedingID:   "proc-123",
+				SlotID:         42,
+				Date:           "2025-10-03T11:30:00",
+				Localization:   "Okopowa 7",
+				QueueID:        "queue-1",
+			},
+		},
+		{
+			name: "successful reservation without details",
+			client: test_utils.NewTestClient(func(req *http.Request) *http.Response {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader([]byte("OK"))),
+				}
+			}),
+			session:    "tok",
+			proceeding: sampleProceeding(),
+			queue:      sampleQueue(),
+			slot:       sampleSlot(),
 		},
 		{
 			name: "unauthorized",
@@ -122,6 +145,23 @@ func TestReserveDateSlot(t *testing.T) {
 			wantErrStr:  "forbidden",
 			wantErrType: &modelerrors.ForbiddenError{},
 		},
+		{
+			name: "slot taken",
+			client: test_utils.NewTestClient(func(req *http.Request) *http.Response {
+				return &http.Response{
+					StatusCode: http.StatusConflict,
+					Status:     "409 Conflict",
+					Body:       io.NopCloser(bytes.NewReader([]byte("taken"))),
+				}
+			}),
+			session:     "tok",
+			proceeding:  sampleProceeding(),
+			queue:       sampleQueue(),
+			slot:        sampleSlot(),
+			wantErrStr:  "already taken",
+			wantErrType: &modelerrors.SlotTakenError{},
+			wantKind:    modelerrors.ErrSlotTaken,
+		},
 		{
 			name: "server error",
 			client: test_utils.NewTestClient(func(req *http.Request) *http.Response {
@@ -137,11 +177,64 @@ func TestReserveDateSlot(t *testing.T) {
 			slot:       sampleSlot(),
 			wantErrStr: "request for 2025-10-03T11:30:00 failed with status: 418 I'm a teapot",
 		},
+		{
+			name:        "slot taken by code",
+			client:      rejectingClient(http.StatusBadRequest, "400 Bad Request", `{"code":"slotNotAvailable","message":"Termin jest już zajęty"}`),
+			session:     "tok",
+			proceeding:  sampleProceeding(),
+			queue:       sampleQueue(),
+			slot:        sampleSlot(),
+			wantErrStr:  "400 Bad Request, Termin jest już zajęty",
+			wantErrType: &modelerrors.SlotTakenError{},
+			wantKind:    modelerrors.ErrSlotTaken,
+		},
+		{
+			name:        "invalid person data",
+			client:      rejectingClient(http.StatusUnprocessableEntity, "422 Unprocessable Entity", `{"message":"Invalid date of birth"}`),
+			session:     "tok",
+			proceeding:  sampleProceeding(),
+			queue:       sampleQueue(),
+			slot:        sampleSlot(),
+			wantErrStr:  "person data of the proceeding was rejected: 422 Unprocessable Entity, Invalid date of birth",
+			wantErrType: &modelerrors.InvalidPersonDataError{},
+		},
+		{
+			name:        "proceeding not eligible",
+			client:      rejectingClient(http.StatusConflict, "409 Conflict", `{"code":"ProceedingNotEligible","message":"Proceeding is closed"}`),
+			session:     "tok",
+			proceeding:  sampleProceeding(),
+			queue:       sampleQueue(),
+			slot:        sampleSlot(),
+			wantErrStr:  "can't get an appointment: 409 Conflict, Proceeding is closed",
+			wantErrType: &modelerrors.ProceedingNotEligibleError{},
+		},
+		{
+			name:        "server unavailable",
+			client:      rejectingClient(http.StatusServiceUnavailable, "503 Service Unavailable", "<html>Przerwa techniczna</html>"),
+			session:     "tok",
+			proceeding:  sampleProceeding(),
+			queue:       sampleQueue(),
+			slot:        sampleSlot(),
+			wantErrStr:  "failed with status: 503 Service Unavailable",
+			wantErrType: &modelerrors.PortalError{},
+			wantKind:    modelerrors.ErrMaintenance,
+		},
+		{
+			name:        "unknown code",
+			client:      rejectingClient(http.StatusBadRequest, "400 Bad Request", `{"code":"Other","message":"Something went wrong"}`),
+			session:     "tok",
+			proceeding:  sampleProceeding(),
+			queue:       sampleQueue(),
+			slot:        sampleSlot(),
+			wantErrStr:  "failed with status: 400 Bad Request, Something went wrong",
+			wantErrType: &modelerrors.PortalError{},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := ReserveDateSlot(tc.client, tc.session, tc.proceeding, tc.queue, tc.slot)
+			t.Parallel()
+			result, err := ReserveDateSlot(context.Background(), test_utils.NewInpolClient(tc.client, tc.session), tc.proceeding, tc.queue, tc.slot)
 
 			if tc.wantErrStr != "" {
 				assert.Error(t, err)
@@ -153,9 +246,23 @@ func TestReserveDateSlot(t *testing.T) {
 						tc.wantErrType, err,
 					)
 				}
+				if tc.wantKind != nil {
+					assert.ErrorIs(t, err, tc.wantKind)
+				}
 			} else {
 				assert.NoError(t, err)
 			}
+			assert.Equal(t, tc.wantResult, result)
 		})
 	}
 }
+
+func rejectingClient(statusCode int, status, body string) *http.Client {
+	return test_utils.NewTestClient(func(req *http.Request) *http.Response {
+		return &http.Response{
+			StatusCode: statusCode,
+			Status:     status,
+			Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package requests
+
+import (
+	"bot-main/models"
+	modelerrors "bot-main/models/errors"
+	"bot-main/output"
+	"bot-main/requests/cookiesinit"
+	"bot-main/requests/inpol"
+	"bot-main/requests/login"
+	"bot-main/utils"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// session is an inpol client together with the login data needed
+// to restore it when the portal stops accepting the token.
+type session struct {
+	client    *inpol.Client
+	loginData models.LoginData
+	// How many times in a row we may log in again before giving up,
+	// so a broken account doesn't get locked by endless sign-ins.
+	maxRelogins int
+	// How long a forbidden reservation may be retried with refreshed cookies.
+	forbiddenRetryBudget time.Duration
+
+	mu       sync.Mutex
+	relogins int
+	// Re-logins done so far, calls failing at the same time on concurrent
+	// lookups sign in once.
+	loginGeneration int
+	loginMu         sync.Mutex
+}
+
+func newSession(client *inpol.Client, applicationData models.ApplicationData) *session {
+	return &session{
+		client:               client,
+		loginData:            applicationData.LoginData,
+		maxRelogins:          applicationData.MaxConsecutiveRelogins,
+		forbiddenRetryBudget: applicationData.ForbiddenRetryBudget,
+	}
+}
+
+// login initializes cookies and signs in, the token is kept by the client.
+func (s *session) login(ctx context.Context) error {
+	output.Println(ctx)
+	output.Println(ctx, "RequestPipeline, initializing cookies...")
+	err := cookiesinit.CookiesInit(ctx, s.client)
+	if err != nil {
+		return fmt.Errorf("RequestPipeline error during initializing cookies: %w", err)
+	}
+
+	//////////////////////////////////////////////////////
+	if err = sleep(ctx, randomPause()); err != nil {
+		return err
+	}
+
+	output.Println(ctx)
+	output.Println(ctx, "RequestPipeline, trying to login...")
+	sessionToken, err := login.Login(ctx, s.client, s.loginData)
+	if err != nil {
+		return fmt.Errorf("RequestPipeline error during login: %w", err)
+	}
+	output.Printf(ctx, "Login request completed successfully, token: %s.\n", utils.MaskSecret(sessionToken))
+	return nil
+}
+
+// refreshCookies drops all cookies and gets new ones from the login page.
+// The token is kept, if the portal doesn't accept it with the new cookies
+// call logs in again.
+func (s *session) refreshCookies(ctx context.Context) error {
+	err := s.client.ResetCookies()
+	if err != nil {
+		return err
+	}
+	return cookiesinit.CookiesInit(ctx, s.client)
+}
+
+// call runs the step and, when it fails because the session expired,
+// logs in again and repeats the step.
+func (s *session) call(ctx context.Context, stepName string, step func() error) error {
+	for {
+		generation := s.currentLoginGeneration()
+		err := step()
+		var unauthorizedError modelerrors.UnauthorizedError
+		if !errors.As(err, &unauthorizedError) {
+			if err == nil {
+				s.resetRelogins()
+			}
+			return err
+		}
+		if err = s.relogin(ctx, stepName, generation, err); err != nil {
+			return err
+		}
+	}
+}
+
+// relogin logs in again, unless another call has already done it since
+// the step was started.
+func (s *session) relogin(ctx context.Context, stepName string, generation int, cause error) error {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	if s.currentLoginGeneration() != generation {
+		return nil
+	}
+
+	relogins, ok := s.nextRelogin()
+	if !ok {
+		return fmt.Errorf("RequestPipeline, %s is still unauthorized after %d consecutive re-logins: %w", stepName, s.maxRelogins, cause)
+	}
+	output.Println(ctx)
+	output.Printf(ctx, "RequestPipeline, session expired during %s, logging in again (%d/%d)...\n", stepName, relogins, s.maxRelogins)
+	if err := sleep(ctx, randomPause()); err != nil {
+		return err
+	}
+	if err := s.login(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loginGeneration++
+	return nil
+}
+
+func (s *session) currentLoginGeneration() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loginGeneration
+}
+
+func (s *session) nextRelogin() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.relogins >= s.maxRelogins {
+		return s.relogins, false
+	}
+	s.relogins++
+	return s.relogins, true
+}
+
+func (s *session) resetRelogins() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.relogins = 0
+}
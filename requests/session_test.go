@@ -0,0 +1,84 @@
+package requests
+
+import (
+	"bot-main/models"
+	modelerrors "bot-main/models/errors"
+	test_utils "bot-main/tests/utils"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePortal answers login page and sign-in requests and counts sign-ins,
+// the rest of requests are answered by api.
+func fakePortal(signIns *atomic.Int32, api func(req *http.Request) *http.Response) *http.Client {
+	return test_utils.NewTestClient(func(req *http.Request) *http.Response {
+		switch req.URL.Path {
+		case "/login":
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(nil))}
+		case "/identity/sign-in":
+			signIns.Add(1)
+			body := `{"isAuthSuccessful":true,"token":"fresh-token"}`
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+		}
+		return api(req)
+	})
+}
+
+func statusResponse(statusCode int) *http.Response {
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     http.StatusText(statusCode),
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+	}
+}
+
+func TestSessionCallRelogsInOnUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	var signIns atomic.Int32
+	httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+		if req.Header.Get("Authorization") != "Bearer fresh-token" {
+			return statusResponse(http.StatusUnauthorized)
+		}
+		return statusResponse(http.StatusOK)
+	})
+	s := newSession(test_utils.NewInpolClient(httpClient, "expired-token"), models.ApplicationData{MaxConsecutiveRelogins: 2})
+
+	calls := 0
+	err := s.call(context.Background(), "test step", func() error {
+		calls++
+		if s.client.Token() != "fresh-token" {
+			return modelerrors.UnauthorizedError{Message: "unauthorized"}
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, int32(1), signIns.Load())
+	assert.Equal(t, 0, s.relogins)
+}
+
+func TestSessionCallGivesUpAfterMaxRelogins(t *testing.T) {
+	t.Parallel()
+
+	var signIns atomic.Int32
+	httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+		return statusResponse(http.StatusUnauthorized)
+	})
+	s := newSession(test_utils.NewInpolClient(httpClient, "expired-token"), models.ApplicationData{MaxConsecutiveRelogins: 2})
+
+	err := s.call(context.Background(), "test step", func() error {
+		return modelerrors.UnauthorizedError{Message: "unauthorized"}
+	})
+
+	assert.ErrorContains(t, err, "still unauthorized after 2 consecutive re-logins")
+	assert.ErrorAs(t, err, &modelerrors.UnauthorizedError{})
+	assert.Equal(t, int32(2), signIns.Load())
+}
@@ -0,0 +1,137 @@
+package requests
+
+import (
+	"bot-main/models"
+	"bot-main/output"
+	"bot-main/requests/dateslots"
+	"bot-main/selection"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// slotLookup asks for the slots of one queue date.
+type slotLookup struct {
+	queue models.ReservationQueue
+	// Position of the queue in the preference list.
+	queueRank int
+	date      string
+	// Slots must start before it when rescheduling.
+	bookBefore time.Time
+}
+
+type slotLookupResult struct {
+	lookup slotLookup
+	// Free slots matching the preferences.
+	candidates []selection.Candidate
+	err        error
+}
+
+// lookupSlots runs the lookups in the given order on a pool of at most
+// LookupConcurrency workers and sends results as soon as they arrive.
+// The channel is closed when all started lookups are done, cancelling ctx
+// stops starting new ones.
+func (w *watcher) lookupSlots(ctx context.Context, proceedingData *models.DetailedProceedingData, lookups []slotLookup) <-chan slotLookupResult {
+	workers := min(max(w.applicationData.LookupConcurrency, 1), len(lookups))
+	jobs := make(chan slotLookup)
+	// Buffered, so workers never wait for a reader which stopped reading.
+	results := make(chan slotLookupResult, len(lookups))
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for lookup := range jobs {
+				results <- w.runLookup(ctx, proceedingData, lookup)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(results)
+		defer wg.Wait()
+		defer close(jobs)
+		for _, lookup := range lookups {
+			select {
+			case jobs <- lookup:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return results
+}
+
+// runLookup turns a panic of the lookup into its error, a worker goroutine
+// isn't covered by the recover of the account and would crash all of them.
+func (w *watcher) runLookup(ctx context.Context, proceedingData *models.DetailedProceedingData, lookup slotLookup) (result slotLookupResult) {
+	result.lookup = lookup
+	defer func() {
+		if r := recover(); r != nil {
+			output.Printf(ctx, "RequestPipeline, lookup of date slots for %s panicked: %v\n%s", lookup.date, r, debug.Stack())
+			result.err = fmt.Errorf("❌ RequestPipeline, lookup of date slots for %s panicked: %v", lookup.date, r)
+		}
+	}()
+	result.candidates, result.err = w.lookupDateSlots(ctx, proceedingData, lookup)
+	return result
+}
+
+// lookupDateSlots gets slots of the queue date and gives back those which
+// still have free places and match the preferences.
+func (w *watcher) lookupDateSlots(ctx context.Context, proceedingData *models.DetailedProceedingData, lookup slotLookup) ([]selection.Candidate, error) {
+	s := w.session
+	if err := sleep(ctx, randomPause()); err != nil {
+		return nil, err
+	}
+
+	output.Printf(ctx, "RequestPipeline, trying to get date slots for date %s at %s...\n", lookup.date, lookup.queue.Localization)
+	var queueDateSlots []models.Slot
+	err := s.call(ctx, "getting queue date slots", func() (err error) {
+		queueDateSlots, err = dateslots.GetReservationQueueDateSlots(ctx, s.client, proceedingData, lookup.queue, lookup.date)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("RequestPipeline error during getting queue date slots: %w", err)
+	}
+	output.Printf(ctx, "Get queue date slots for %s at %s completed successfully, date slots:\n", lookup.date, lookup.queue.Localization)
+	printData(ctx, queueDateSlots)
+
+	var candidates []selection.Candidate
+	for _, slot := range queueDateSlots {
+		if slot.Count <= 0 || !selection.SlotAllowed(slot, w.applicationData.Preferences) {
+			continue
+		}
+		// Slots passing SlotAllowed have a valid date.
+		candidate, _ := selection.NewCandidate(lookup.queue, lookup.queueRank, slot)
+		if !lookup.bookBefore.IsZero() && !candidate.Time.Before(lookup.bookBefore) {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// orderLookups sorts lookups the way the selector would rank their dates,
+// so dates most likely to hold the best slot are looked up first.
+func orderLookups(selector selection.SlotSelector, lookups []slotLookup) []slotLookup {
+	candidates := make([]selection.Candidate, 0, len(lookups))
+	for i, lookup := range lookups {
+		// Dates were checked by DateAllowed, so they parse.
+		day, _ := time.ParseInLocation(time.DateOnly, lookup.date, selection.Warsaw)
+		candidates = append(candidates, selection.Candidate{
+			Queue:     lookup.queue,
+			QueueRank: lookup.queueRank,
+			// Slot ID keeps the lookup index to find it after ranking.
+			Slot: models.Slot{ID: i, Date: lookup.date},
+			Time: day,
+		})
+	}
+	ordered := make([]slotLookup, 0, len(lookups))
+	for _, candidate := range selector.Rank(candidates) {
+		ordered = append(ordered, lookups[candidate.Slot.ID])
+	}
+	return ordered
+}
@@ -0,0 +1,102 @@
+package requests
+
+import (
+	"bot-main/models"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Summary is what the pipeline managed to do before it returned,
+// it's printed when the bot stops for any reason (including Ctrl+C).
+type Summary struct {
+	mu sync.Mutex
+
+	startedAt     time.Time
+	steps         []string
+	watchAttempts int
+
+	// Slots which reservation request was sent but its outcome is not known.
+	pendingSlots []models.Slot
+	// Slots reserved successfully, one per watched proceeding.
+	reservedSlots []models.Slot
+	// Slots which reservation was accepted but not found in the proceeding.
+	unconfirmedSlots []models.Slot
+}
+
+func NewSummary() *Summary {
+	return &Summary{startedAt: time.Now()}
+}
+
+func (s *Summary) stepDone(format string, args ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, fmt.Sprintf(format, args...))
+}
+
+func (s *Summary) watchAttempt() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watchAttempts++
+}
+
+func (s *Summary) reservationStarted(slot models.Slot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pendingSlots = append(s.pendingSlots, slot)
+}
+
+func (s *Summary) reservationFinished(slot models.Slot, reserved bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pendingSlots = slices.DeleteFunc(s.pendingSlots, func(pending models.Slot) bool {
+		return pending.ID == slot.ID
+	})
+	if reserved {
+		s.reservedSlots = append(s.reservedSlots, slot)
+	}
+}
+
+func (s *Summary) reservationUnconfirmed(slot models.Slot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pendingSlots = slices.DeleteFunc(s.pendingSlots, func(pending models.Slot) bool {
+		return pending.ID == slot.ID
+	})
+	s.unconfirmedSlots = append(s.unconfirmedSlots, slot)
+}
+
+// Print writes the summary to w.
+func (s *Summary) Print(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "---")
+	fmt.Fprintf(w, "Summary, bot was running for %s.\n", time.Since(s.startedAt).Round(time.Second))
+	if len(s.steps) == 0 {
+		fmt.Fprintln(w, "No steps were completed.")
+	}
+	for _, step := range s.steps {
+		fmt.Fprintf(w, "✅ %s\n", step)
+	}
+	if s.watchAttempts > 0 {
+		fmt.Fprintf(w, "Slots were checked %d time(s).\n", s.watchAttempts)
+	}
+	for _, slot := range s.pendingSlots {
+		fmt.Fprintf(w, "⚠️ Reservation request for slot %s (ID %d) was sent but its outcome is unknown, check the portal!\n",
+			slot.Date, slot.ID)
+	}
+	for _, slot := range s.reservedSlots {
+		fmt.Fprintf(w, "✅ Slot %s (ID %d) is reserved.\n", slot.Date, slot.ID)
+	}
+	for _, slot := range s.unconfirmedSlots {
+		fmt.Fprintf(w, "⚠️ Reservation of slot %s (ID %d) was accepted but it's unconfirmed, check the portal!\n", slot.Date, slot.ID)
+	}
+	if len(s.reservedSlots) == 0 && len(s.unconfirmedSlots) == 0 {
+		fmt.Fprintln(w, "❌ No slot was reserved.")
+	}
+	fmt.Fprintln(w, "---")
+}
@@ -1,37 +1,132 @@
 package requests
 
 import (
-	"compress/flate"
+	modelerrors "bot-main/models/errors"
 	"compress/gzip"
+	"compress/zlib"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 )
 
+type decoder func(body io.Reader) (io.ReadCloser, error)
+
+// Encodings DecompressingTransport can decode, in the order they are advertised.
+var supportedEncodings = []string{"gzip", "deflate", "br", "zstd"}
+
+var decoders = map[string]decoder{
+	"gzip": func(body io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(body)
+	},
+	// HTTP deflate is the zlib format, not raw DEFLATE.
+	"deflate": func(body io.Reader) (io.ReadCloser, error) {
+		return zlib.NewReader(body)
+	},
+	"br": func(body io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(body)), nil
+	},
+	"zstd": func(body io.Reader) (io.ReadCloser, error) {
+		reader, err := zstd.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return reader.IOReadCloser(), nil
+	},
+}
+
+// AcceptEncoding is the Accept-Encoding header value
+// matching what DecompressingTransport can decode.
+func AcceptEncoding() string {
+	return strings.Join(supportedEncodings, ", ")
+}
+
+// DecompressingTransport advertises the encodings it supports and decodes
+// response bodies, so callers always get plain content.
 type DecompressingTransport struct {
 	Transport http.RoundTripper
 }
 
 func (t *DecompressingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Round trippers must not modify the original request.
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", AcceptEncoding())
+
 	resp, err := t.Transport.RoundTrip(req)
 	if err != nil {
 		return nil, err
 	}
 
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		reader, err := gzip.NewReader(resp.Body)
+	contentEncoding := resp.Header.Get("Content-Encoding")
+	if contentEncoding == "" {
+		return resp, nil
+	}
+
+	body, err := decodeBody(resp.Body, contentEncoding)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp, nil
+}
+
+// decodeBody undoes the encodings in the reverse order they were applied.
+// Closing the result closes all decoders and the original body.
+func decodeBody(body io.ReadCloser, contentEncoding string) (io.ReadCloser, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	decoded := &decodedBody{Reader: body, closers: []io.Closer{body}}
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if encoding == "" || encoding == "identity" {
+			continue
+		}
+		decode, ok := decoders[encoding]
+		if !ok {
+			decoded.closeDecoders()
+			return nil, modelerrors.UnsupportedContentEncodingError{
+				Message:  fmt.Sprintf("❌ Response is encoded with unsupported content encoding %q", contentEncoding),
+				Encoding: encoding,
+			}
+		}
+		reader, err := decode(decoded.Reader)
 		if err != nil {
-			return nil, fmt.Errorf("error creating gzip reader: %v", err)
+			decoded.closeDecoders()
+			return nil, fmt.Errorf("error creating %s reader: %w", encoding, err)
 		}
-		resp.Body = reader
-		resp.Header.Del("Content-Encoding")
-		resp.Header.Del("Content-Length")
-	case "deflate":
-		reader := flate.NewReader(resp.Body)
-		resp.Body = reader
-		resp.Header.Del("Content-Encoding")
-		resp.Header.Del("Content-Length")
+		decoded.Reader = reader
+		decoded.closers = append(decoded.closers, reader)
 	}
+	return decoded, nil
+}
 
-	return resp, nil
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if closeErr := b.closers[i].Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// closeDecoders closes the decoders created so far, the original body
+// is left to the caller.
+func (b *decodedBody) closeDecoders() {
+	for i := len(b.closers) - 1; i > 0; i-- {
+		b.closers[i].Close()
+	}
 }
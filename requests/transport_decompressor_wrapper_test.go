@@ -0,0 +1,117 @@
+package requests
+
+import (
+	modelerrors "bot-main/models/errors"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func encode(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(&buf)
+	case "deflate":
+		writer = zlib.NewWriter(&buf)
+	case "br":
+		writer = brotli.NewWriter(&buf)
+	case "zstd":
+		var err error
+		writer, err = zstd.NewWriter(&buf)
+		assert.NoError(t, err)
+	default:
+		return data
+	}
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestDecompressingTransport(t *testing.T) {
+	t.Parallel()
+
+	plain := []byte(`["2025-08-10T00:00:00"]`)
+
+	testCases := []struct {
+		name            string
+		contentEncoding string
+		body            []byte
+		wantErrStr      string
+		wantErrType     any
+	}{
+		{name: "plain", contentEncoding: "", body: plain},
+		{name: "gzip", contentEncoding: "gzip", body: encode(t, "gzip", plain)},
+		{name: "deflate", contentEncoding: "deflate", body: encode(t, "deflate", plain)},
+		{name: "brotli", contentEncoding: "br", body: encode(t, "br", plain)},
+		{name: "zstd", contentEncoding: "zstd", body: encode(t, "zstd", plain)},
+		{name: "stacked encodings", contentEncoding: "gzip, br", body: encode(t, "br", encode(t, "gzip", plain))},
+		{
+			name:            "unsupported encoding",
+			contentEncoding: "compress",
+			body:            plain,
+			wantErrStr:      `unsupported content encoding "compress"`,
+			wantErrType:     &modelerrors.UnsupportedContentEncodingError{},
+		},
+		{
+			name:            "broken gzip",
+			contentEncoding: "gzip",
+			body:            plain,
+			wantErrStr:      "error creating gzip reader",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			transport := &DecompressingTransport{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "gzip, deflate, br, zstd", req.Header.Get("Accept-Encoding"))
+				header := make(http.Header)
+				if tc.contentEncoding != "" {
+					header.Set("Content-Encoding", tc.contentEncoding)
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     header,
+					Body:       io.NopCloser(bytes.NewReader(tc.body)),
+				}, nil
+			})}
+			req, _ := http.NewRequest("GET", "https://fake/", nil)
+
+			resp, err := transport.RoundTrip(req)
+
+			// Original request is left untouched.
+			assert.Empty(t, req.Header.Get("Accept-Encoding"))
+			if tc.wantErrStr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErrStr)
+				if tc.wantErrType != nil {
+					assert.ErrorAs(t, err, tc.wantErrType)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.NoError(t, resp.Body.Close())
+			assert.Equal(t, plain, body)
+			assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package requests
+
+import (
+	"bot-main/models"
+	"bot-main/requests/inpol"
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultRateLimits keep the bot well below what a person clicking
+// through the portal would send.
+func DefaultRateLimits() map[inpol.EndpointFamily]models.RateLimit {
+	return map[inpol.EndpointFamily]models.RateLimit{
+		inpol.FamilyLogin:       {PerMinute: 6, Burst: 2},
+		inpol.FamilyProceedings: {PerMinute: 20, Burst: 3},
+		inpol.FamilyDates:       {PerMinute: 20, Burst: 3},
+		inpol.FamilySlots:       {PerMinute: 40, Burst: 5},
+		inpol.FamilyReserve:     {PerMinute: 30, Burst: 3},
+	}
+}
+
+// NewRateLimiters creates token bucket limiters, families with zero
+// requests per minute are not limited.
+func NewRateLimiters(limits map[inpol.EndpointFamily]models.RateLimit) map[inpol.EndpointFamily]*rate.Limiter {
+	limiters := make(map[inpol.EndpointFamily]*rate.Limiter, len(limits))
+	for family, limit := range limits {
+		if limit.PerMinute <= 0 {
+			continue
+		}
+		limiters[family] = rate.NewLimiter(rate.Limit(limit.PerMinute/60), max(limit.Burst, 1))
+	}
+	return limiters
+}
+
+// RateLimitingTransport holds requests back to the rate of their endpoint
+// family and spends the daily budget of the account for every request sent.
+type RateLimitingTransport struct {
+	Transport http.RoundTripper
+	Limiters  map[inpol.EndpointFamily]*rate.Limiter
+	Budget    *DailyBudget
+}
+
+func (t *RateLimitingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if limiter, ok := t.Limiters[inpol.RequestEndpoint(req).Family()]; ok {
+		if err := limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.Budget.Take(); err != nil {
+		return nil, err
+	}
+	return t.Transport.RoundTrip(req)
+}
@@ -0,0 +1,55 @@
+package requests
+
+import (
+	modelerrors "bot-main/models/errors"
+	"bot-main/requests/inpol"
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitingTransport(t *testing.T) {
+	t.Parallel()
+
+	sent := 0
+	transport := &RateLimitingTransport{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			sent++
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}),
+		Limiters: map[inpol.EndpointFamily]*rate.Limiter{
+			// Single token which is never refilled.
+			inpol.FamilySlots: rate.NewLimiter(rate.Limit(0.0001), 1),
+		},
+		Budget: NewDailyBudget(filepath.Join(t.TempDir(), "budget.json"), "user@example.com", 3),
+	}
+	newRequest := func(ctx context.Context, endpoint inpol.Endpoint) *http.Request {
+		req, _ := http.NewRequestWithContext(inpol.WithEndpoint(ctx, endpoint), "POST", "https://fake/", nil)
+		return req
+	}
+
+	_, err := transport.RoundTrip(newRequest(context.Background(), inpol.EndpointSlots))
+	assert.NoError(t, err)
+
+	// Next slots request has to wait for the limiter, other families don't.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = transport.RoundTrip(newRequest(ctx, inpol.EndpointSlots))
+	assert.Error(t, err)
+	_, err = transport.RoundTrip(newRequest(context.Background(), inpol.EndpointDates))
+	assert.NoError(t, err)
+	_, err = transport.RoundTrip(newRequest(context.Background(), inpol.EndpointDates))
+	assert.NoError(t, err)
+
+	// Budget of 3 requests is spent.
+	_, err = transport.RoundTrip(newRequest(context.Background(), inpol.EndpointDates))
+	assert.ErrorAs(t, err, &modelerrors.BudgetExhaustedError{})
+	assert.Equal(t, 3, sent)
+}
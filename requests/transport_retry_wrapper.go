@@ -0,0 +1,181 @@
+package requests
+
+import (
+	modelerrors "bot-main/models/errors"
+	"bot-main/output"
+	"bot-main/requests/inpol"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy tells RetryingTransport when and how often a request may be repeated.
+type RetryPolicy struct {
+	// Attempts in total, 1 or less means the request is never repeated.
+	MaxAttempts int
+	// Backoff grows exponentially from BaseDelay up to MaxDelay, with jitter.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Longest Retry-After we are ready to wait, responses asking
+	// for more are returned as they are.
+	MaxRetryAfter time.Duration
+	// Response statuses worth repeating the request for.
+	RetryStatuses []int
+	// Whether network errors after the request could have reached the portal
+	// are retried. When false only failures to connect are retried, as
+	// otherwise we can't know if the portal processed the request.
+	RetryAmbiguousErrors bool
+}
+
+// ReadRetryPolicy is for requests which can be repeated freely.
+var ReadRetryPolicy = RetryPolicy{
+	MaxAttempts:          4,
+	BaseDelay:            500 * time.Millisecond,
+	MaxDelay:             10 * time.Second,
+	MaxRetryAfter:        time.Minute,
+	RetryStatuses:        []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	RetryAmbiguousErrors: true,
+}
+
+// ReserveRetryPolicy repeats a reservation only when the portal certainly
+// didn't process it, anything else risks a double booking.
+var ReserveRetryPolicy = RetryPolicy{
+	MaxAttempts:   3,
+	BaseDelay:     200 * time.Millisecond,
+	MaxDelay:      2 * time.Second,
+	MaxRetryAfter: 5 * time.Second,
+	RetryStatuses: []int{http.StatusTooManyRequests},
+}
+
+func DefaultRetryPolicies() map[inpol.Endpoint]RetryPolicy {
+	return map[inpol.Endpoint]RetryPolicy{
+		inpol.EndpointLoginPage:         ReadRetryPolicy,
+		inpol.EndpointLogin:             ReadRetryPolicy,
+		inpol.EndpointActiveProceedings: ReadRetryPolicy,
+		inpol.EndpointProceeding:        ReadRetryPolicy,
+		inpol.EndpointReservationQueues: ReadRetryPolicy,
+		inpol.EndpointDates:             ReadRetryPolicy,
+		inpol.EndpointSlots:             ReadRetryPolicy,
+		inpol.EndpointReserve:           ReserveRetryPolicy,
+	}
+}
+
+// RetryingTransport repeats failed requests according to the policy of their
+// endpoint. Requests of endpoints without a policy are sent once.
+type RetryingTransport struct {
+	Transport http.RoundTripper
+	Policies  map[inpol.Endpoint]RetryPolicy
+}
+
+func (t *RetryingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := inpol.RequestEndpoint(req)
+	policy, ok := t.Policies[endpoint]
+	if !ok || policy.MaxAttempts <= 1 || (req.Body != nil && req.GetBody == nil) {
+		return t.Transport.RoundTrip(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("RetryingTransport error rewinding request body: %w", err)
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := t.Transport.RoundTrip(attemptReq)
+		if attempt >= policy.MaxAttempts {
+			return resp, err
+		}
+
+		var delay time.Duration
+		var reason string
+		if err != nil {
+			if !policy.shouldRetryError(err) {
+				return nil, err
+			}
+			delay = policy.backoff(attempt)
+			reason = err.Error()
+		} else {
+			if !slices.Contains(policy.RetryStatuses, resp.StatusCode) {
+				return resp, nil
+			}
+			delay = policy.backoff(attempt)
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if retryAfter > policy.MaxRetryAfter {
+					return resp, nil
+				}
+				delay = max(delay, retryAfter)
+			}
+			reason = resp.Status
+			// Body is dropped, draining it lets the connection be reused.
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		output.Printf(req.Context(), "RetryingTransport, %s request failed (%s), retrying in %s (attempt %d/%d)...\n",
+			endpoint, reason, delay.Round(time.Millisecond), attempt+1, policy.MaxAttempts)
+		if err = sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p RetryPolicy) shouldRetryError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var budgetExhaustedError modelerrors.BudgetExhaustedError
+	if errors.As(err, &budgetExhaustedError) {
+		return false
+	}
+	return p.RetryAmbiguousErrors || isConnectError(err)
+}
+
+// backoff is the "full jitter" exponential delay before the next attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay << (attempt - 1)
+	if ceiling > p.MaxDelay || ceiling <= 0 {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// isConnectError reports errors which happened before the request was sent.
+func isConnectError(err error) bool {
+	var opError *net.OpError
+	if errors.As(err, &opError) && opError.Op == "dial" {
+		return true
+	}
+	var dnsError *net.DNSError
+	return errors.As(err, &dnsError)
+}
+
+// parseRetryAfter understands both forms of the header: delay in seconds and HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,185 @@
+package requests
+
+import (
+	"bot-main/requests/inpol"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fastPolicy(policy RetryPolicy) RetryPolicy {
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = time.Millisecond
+	return policy
+}
+
+type fakeAnswer struct {
+	status     int
+	retryAfter string
+	err        error
+}
+
+func TestRetryingTransport(t *testing.T) {
+	t.Parallel()
+
+	dialError := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	testCases := []struct {
+		name         string
+		endpoint     inpol.Endpoint
+		answers      []fakeAnswer
+		wantAttempts int
+		wantStatus   int
+		wantErr      error
+	}{
+		{
+			name:         "read retried after server errors",
+			endpoint:     inpol.EndpointDates,
+			answers:      []fakeAnswer{{status: 503, retryAfter: "0"}, {status: 502}, {status: 200}},
+			wantAttempts: 3,
+			wantStatus:   200,
+		},
+		{
+			name:         "read retried after ambiguous network error",
+			endpoint:     inpol.EndpointSlots,
+			answers:      []fakeAnswer{{err: io.ErrUnexpectedEOF}, {status: 200}},
+			wantAttempts: 2,
+			wantStatus:   200,
+		},
+		{
+			name:         "read gives up after max attempts",
+			endpoint:     inpol.EndpointDates,
+			answers:      []fakeAnswer{{status: 500}, {status: 500}, {status: 500}, {status: 500}, {status: 200}},
+			wantAttempts: 4,
+			wantStatus:   500,
+		},
+		{
+			name:         "too long retry after is not waited for",
+			endpoint:     inpol.EndpointDates,
+			answers:      []fakeAnswer{{status: 429, retryAfter: "3600"}, {status: 200}},
+			wantAttempts: 1,
+			wantStatus:   429,
+		},
+		{
+			name:         "client errors are not retried",
+			endpoint:     inpol.EndpointDates,
+			answers:      []fakeAnswer{{status: 401}, {status: 200}},
+			wantAttempts: 1,
+			wantStatus:   401,
+		},
+		{
+			name:         "reserve retried when rate limited",
+			endpoint:     inpol.EndpointReserve,
+			answers:      []fakeAnswer{{status: 429, retryAfter: "0"}, {status: 200}},
+			wantAttempts: 2,
+			wantStatus:   200,
+		},
+		{
+			name:         "reserve retried when connection failed",
+			endpoint:     inpol.EndpointReserve,
+			answers:      []fakeAnswer{{err: dialError}, {status: 200}},
+			wantAttempts: 2,
+			wantStatus:   200,
+		},
+		{
+			name:         "reserve not retried after server error",
+			endpoint:     inpol.EndpointReserve,
+			answers:      []fakeAnswer{{status: 503}, {status: 200}},
+			wantAttempts: 1,
+			wantStatus:   503,
+		},
+		{
+			name:         "reserve not retried after ambiguous network error",
+			endpoint:     inpol.EndpointReserve,
+			answers:      []fakeAnswer{{err: io.ErrUnexpectedEOF}, {status: 200}},
+			wantAttempts: 1,
+			wantErr:      io.ErrUnexpectedEOF,
+		},
+		{
+			name:         "untagged request sent once",
+			endpoint:     "",
+			answers:      []fakeAnswer{{status: 503}, {status: 200}},
+			wantAttempts: 1,
+			wantStatus:   503,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			attempts := 0
+			transport := &RetryingTransport{
+				Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					body, _ := io.ReadAll(req.Body)
+					assert.Equal(t, `{"slotId":42}`, string(body))
+					answer := tc.answers[attempts]
+					attempts++
+					if answer.err != nil {
+						return nil, answer.err
+					}
+					header := make(http.Header)
+					if answer.retryAfter != "" {
+						header.Set("Retry-After", answer.retryAfter)
+					}
+					return &http.Response{
+						StatusCode: answer.status,
+						Status:     http.StatusText(answer.status),
+						Header:     header,
+						Body:       io.NopCloser(strings.NewReader("")),
+					}, nil
+				}),
+				Policies: map[inpol.Endpoint]RetryPolicy{
+					inpol.EndpointDates:   fastPolicy(ReadRetryPolicy),
+					inpol.EndpointSlots:   fastPolicy(ReadRetryPolicy),
+					inpol.EndpointReserve: fastPolicy(ReserveRetryPolicy),
+				},
+			}
+			ctx := inpol.WithEndpoint(context.Background(), tc.endpoint)
+			req, _ := http.NewRequestWithContext(ctx, "POST", "https://fake/", bytes.NewBufferString(`{"slotId":42}`))
+
+			resp, err := transport.RoundTrip(req)
+
+			assert.Equal(t, tc.wantAttempts, attempts)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 10, 3, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		value     string
+		wantDelay time.Duration
+		wantOk    bool
+	}{
+		{value: "", wantOk: false},
+		{value: "120", wantDelay: 2 * time.Minute, wantOk: true},
+		{value: "-1", wantOk: false},
+		{value: "Fri, 03 Oct 2025 12:00:30 GMT", wantDelay: 30 * time.Second, wantOk: true},
+		{value: "Fri, 03 Oct 2025 11:00:00 GMT", wantDelay: 0, wantOk: true},
+		{value: "soon", wantOk: false},
+	}
+
+	for _, tc := range testCases {
+		delay, ok := parseRetryAfter(tc.value, now)
+		assert.Equal(t, tc.wantOk, ok, tc.value)
+		assert.Equal(t, tc.wantDelay, delay, tc.value)
+	}
+}
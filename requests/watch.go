@@ -0,0 +1,506 @@
+package requests
+
+import (
+	"bot-main/globalvars"
+	"bot-main/models"
+	modelerrors "bot-main/models/errors"
+	"bot-main/notify"
+	"bot-main/output"
+	"bot-main/requests/dates"
+	"bot-main/requests/reserve"
+	"bot-main/selection"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Time given to an already sent reservation request to complete after
+// the pipeline was asked to stop.
+const reserveGracePeriod = 15 * time.Second
+
+// watchTarget is a proceeding together with the queues its slots are looked
+// for in, in order of preference.
+type watchTarget struct {
+	proceedingData *models.DetailedProceedingData
+	queues         []models.ReservationQueue
+	// Appointment being rescheduled, zero when not rescheduling.
+	currentAppointment time.Time
+	// Slots must start before it to beat the current appointment by the margin.
+	bookBefore time.Time
+}
+
+func (t watchTarget) rescheduling() bool {
+	return !t.currentAppointment.IsZero()
+}
+
+// watcher keeps what the watch loop needs between its attempts.
+type watcher struct {
+	session         *session
+	summary         *Summary
+	notifier        *notify.Notifier
+	applicationData models.ApplicationData
+	targets         []watchTarget
+	// Ranks free slots, earliest first when nil.
+	selector selection.SlotSelector
+	// How long a reservation is looked for in the proceeding timeline,
+	// zero means it's checked once.
+	verifyWindow time.Duration
+	// Reservations which outcome is unknown by proceeding ID, the proceeding
+	// timeline is checked for them before the target is reserved again.
+	unknownReservations map[string]selection.Candidate
+}
+
+// watchAndReserve polls dates and slots of every target until a free slot is
+// reserved for each of them. Errors are reported and the loop keeps going,
+// only an unauthorized error the session couldn't recover from or ctx
+// cancellation stops it. Every appointmentCheckEvery attempts the targets
+// are checked for appointments made some other way, such targets are no
+// longer watched. Their stop isn't an error, the summary tells about it.
+// Reservations which weren't confirmed or were rejected because of
+// the proceeding itself are returned as errors.
+func (w *watcher) watchAndReserve(ctx context.Context) ([]models.Slot, error) {
+	interval := w.applicationData.WatchInterval
+	pending := slices.Clone(w.targets)
+	var reservedSlots []models.Slot
+	// Reservations which weren't confirmed or were rejected for good,
+	// their targets aren't watched again.
+	var failed []error
+	for attempt := 1; ; attempt++ {
+		output.Println(ctx)
+		output.Printf(ctx, "RequestPipeline, watch attempt %d, looking for free slots for %d proceeding(s)...\n", attempt, len(pending))
+		w.summary.watchAttempt()
+		pause := jitter(interval)
+		var remaining []watchTarget
+		for i, target := range pending {
+			var slot models.Slot
+			var err error
+			if attempt%appointmentCheckEvery == 0 {
+				err = w.checkNoAppointment(ctx, target)
+			}
+			if err == nil {
+				slot, err = w.watchTarget(ctx, target)
+			}
+			if err == nil {
+				reservedSlots = append(reservedSlots, slot)
+				continue
+			}
+			var unconfirmedError modelerrors.UnconfirmedReservationError
+			if errors.As(err, &unconfirmedError) {
+				output.Printf(ctx, "RequestPipeline, stop watching proceeding %s: %v\n", target.proceedingData.ID, err)
+				failed = append(failed, err)
+				continue
+			}
+			if rejected(err) {
+				output.Printf(ctx, "RequestPipeline, stop watching proceeding %s, its reservations are rejected: %v\n", target.proceedingData.ID, err)
+				w.summary.stepDone("Stopped watching proceeding %s, its reservation was rejected", target.proceedingData.ID)
+				failed = append(failed, err)
+				continue
+			}
+			var appointmentExistsError modelerrors.AppointmentExistsError
+			if errors.As(err, &appointmentExistsError) {
+				output.Printf(ctx, "RequestPipeline, stop watching proceeding %s: %v\n", target.proceedingData.ID, err)
+				w.summary.stepDone("Stopped watching proceeding %s, it already has an appointment (%s event dated %s)",
+					target.proceedingData.ID, globalvars.AppointmentMade, appointmentExistsError.EventDate.Format(time.DateTime))
+				w.notifier.Notify(ctx, notify.EventAppointmentExists, err.Error())
+				continue
+			}
+			if errors.Is(err, errNoFreeSlot) {
+				output.Printf(ctx, "RequestPipeline, no free slots for proceeding %s yet.\n", target.proceedingData.ID)
+				remaining = append(remaining, target)
+				continue
+			}
+			if ctx.Err() != nil {
+				return reservedSlots, ctx.Err()
+			}
+			var unauthorizedError modelerrors.UnauthorizedError
+			if errors.As(err, &unauthorizedError) {
+				return reservedSlots, err
+			}
+			var budgetExhaustedError modelerrors.BudgetExhaustedError
+			if errors.As(err, &budgetExhaustedError) {
+				output.Printf(ctx, "RequestPipeline, watch is paused until %s: %v\n", budgetExhaustedError.ResetAt.Format(time.DateTime), budgetExhaustedError)
+				w.summary.stepDone("Watch paused because daily request budget was exhausted")
+				pause = max(time.Until(budgetExhaustedError.ResetAt), 0) + jitter(interval)
+				// Targets not checked in this attempt wait for the budget too.
+				remaining = append(remaining, pending[i:]...)
+				break
+			}
+			if portalUnavailable(err) {
+				output.Printf(ctx, "RequestPipeline, portal is unavailable, remaining proceedings wait for the next check: %v\n", err)
+				remaining = append(remaining, pending[i:]...)
+				break
+			}
+			output.Printf(ctx, "RequestPipeline, watch attempt %d for proceeding %s failed, will try again: %v\n", attempt, target.proceedingData.ID, err)
+			remaining = append(remaining, target)
+		}
+		pending = remaining
+		if len(pending) == 0 {
+			return reservedSlots, errors.Join(failed...)
+		}
+
+		output.Printf(ctx, "RequestPipeline, next check in %s.\n", pause.Round(time.Second))
+		if err := sleep(ctx, pause); err != nil {
+			return reservedSlots, err
+		}
+	}
+}
+
+// errNoFreeSlot means the target was checked fine, there was just nothing to reserve.
+var errNoFreeSlot = errors.New("no free slot")
+
+// watchTarget looks up free slots of the target once and reserves the best
+// ranked one as soon as it turns up, lookups of other dates may still be
+// in flight then. When the slot is already taken the next ranked one is
+// tried, up to MaxReserveAttempts slots. Before the first reservation
+// the target is checked for an appointment made meanwhile. A reservation
+// which outcome wasn't known is looked for in the proceeding first, so
+// the target isn't booked twice.
+func (w *watcher) watchTarget(ctx context.Context, target watchTarget) (models.Slot, error) {
+	if candidate, ok := w.unknownReservations[target.proceedingData.ID]; ok {
+		booked, err := w.checkUnknownReservation(ctx, target, candidate)
+		if err != nil {
+			return models.Slot{}, err
+		}
+		delete(w.unknownReservations, target.proceedingData.ID)
+		if booked {
+			w.reservationFinished(context.WithoutCancel(ctx), target, candidate.Queue, candidate.Slot, models.ReservationResult{}, nil)
+			if target.rescheduling() {
+				w.rescheduled(ctx, target, candidate)
+			}
+			return candidate.Slot, nil
+		}
+		w.summary.reservationFinished(candidate.Slot, false)
+	}
+
+	lookups, firstErr := w.planLookups(ctx, target)
+	if len(lookups) == 0 {
+		return models.Slot{}, cmp.Or(firstErr, errNoFreeSlot)
+	}
+
+	lookupCtx, cancel := context.WithCancel(ctx)
+	results := w.lookupSlots(lookupCtx, target.proceedingData, lookups)
+	defer func() {
+		cancel()
+		// Lookups still running are waited for, so nothing is printed after return.
+		for range results {
+		}
+	}()
+
+	maxAttempts := max(w.applicationData.MaxReserveAttempts, 1)
+	attempts := 0
+	var takenErr error
+	// Free slots found and not tried yet.
+	var candidates []selection.Candidate
+	for result := range results {
+		if result.err != nil {
+			if stopsWatch(ctx, result.err) {
+				return models.Slot{}, result.err
+			}
+			output.Printf(ctx, "RequestPipeline, checking date %s at %s failed, trying other dates: %v\n", result.lookup.date, result.lookup.queue.Localization, result.err)
+			firstErr = cmp.Or(firstErr, result.err)
+			continue
+		}
+		candidates = append(candidates, result.candidates...)
+		if attempts == 0 && len(candidates) > 0 {
+			if err := w.checkNoAppointment(ctx, target); err != nil {
+				return models.Slot{}, err
+			}
+		}
+		for len(candidates) > 0 {
+			ranked := w.slotSelector().Rank(candidates)
+			best := ranked[0]
+			candidates = ranked[1:]
+			attempts++
+
+			output.Println(ctx)
+			output.Printf(ctx, "RequestPipeline, reservation attempt %d/%d, trying to reserve the best ranked %s at %s for proceeding %s...\n",
+				attempts, maxAttempts, best.Slot.Date, best.Queue.Localization, target.proceedingData.ID)
+			if w.applicationData.DryRun {
+				w.dryRunReservation(ctx, target, best)
+				return best.Slot, nil
+			}
+			result, err := w.reserveSlot(ctx, target, best.Queue, best.Slot)
+			if err == nil {
+				output.Printf(ctx, "RequestPipeline, reservation attempt %d/%d succeeded, reservation:\n", attempts, maxAttempts)
+				printData(ctx, result)
+				// Reported once verified, a stop meanwhile mustn't drop the report.
+				reportCtx := context.WithoutCancel(ctx)
+				if err = w.verifyReservation(ctx, target, best.Queue, best.Slot); err != nil {
+					w.summary.reservationUnconfirmed(best.Slot)
+					w.notifier.Notify(reportCtx, notify.EventReservationUnconfirmed, err.Error())
+					return best.Slot, err
+				}
+				w.reservationFinished(reportCtx, target, best.Queue, best.Slot, result, nil)
+				if target.rescheduling() {
+					w.rescheduled(ctx, target, best)
+				}
+				return best.Slot, nil
+			}
+			var unknownOutcomeError unknownOutcomeError
+			if errors.As(err, &unknownOutcomeError) {
+				if w.unknownReservations == nil {
+					w.unknownReservations = make(map[string]selection.Candidate)
+				}
+				w.unknownReservations[target.proceedingData.ID] = best
+			}
+			var slotTakenError modelerrors.SlotTakenError
+			if !errors.As(err, &slotTakenError) {
+				output.Printf(ctx, "RequestPipeline, reservation attempt %d/%d failed: %v\n", attempts, maxAttempts, err)
+				return models.Slot{}, err
+			}
+			output.Printf(ctx, "RequestPipeline, reservation attempt %d/%d lost, slot %s at %s is already taken.\n",
+				attempts, maxAttempts, best.Slot.Date, best.Queue.Localization)
+			takenErr = err
+			if attempts >= maxAttempts {
+				return models.Slot{}, fmt.Errorf("RequestPipeline gave up after %d reservation attempt(s): %w", attempts, takenErr)
+			}
+		}
+	}
+	if takenErr != nil {
+		return models.Slot{}, fmt.Errorf("RequestPipeline, all %d free slot(s) found were taken: %w", attempts, takenErr)
+	}
+	return models.Slot{}, cmp.Or(firstErr, errNoFreeSlot)
+}
+
+func (w *watcher) slotSelector() selection.SlotSelector {
+	if w.selector == nil {
+		return selection.EarliestSelector{}
+	}
+	return w.selector
+}
+
+// reserveSlot sends the reservation request. It's not cancelled together
+// with ctx: interrupting the POST halfway would leave us not knowing whether
+// the slot was booked, so it gets a short grace period to complete instead.
+// When the portal forbids the reservation, cookies are refreshed and the same
+// slot is tried again while the session forbidden retry budget lasts.
+// Failures are reported here, an accepted reservation stays pending in
+// the summary until it's verified.
+func (w *watcher) reserveSlot(ctx context.Context, target watchTarget, queue models.ReservationQueue, slot models.Slot) (models.ReservationResult, error) {
+	s := w.session
+	reserveCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	stopNotice := context.AfterFunc(ctx, func() {
+		output.Printf(ctx, "RequestPipeline, stop requested, waiting up to %s for the reservation request to complete...\n", reserveGracePeriod)
+		time.AfterFunc(reserveGracePeriod, cancel)
+	})
+	defer stopNotice()
+
+	w.summary.reservationStarted(slot)
+	forbiddenRetryDeadline := time.Now().Add(s.forbiddenRetryBudget)
+	for {
+		var result models.ReservationResult
+		err := s.call(reserveCtx, "reserving date slot", func() (err error) {
+			result, err = reserve.ReserveDateSlot(reserveCtx, s.client, target.proceedingData, queue, slot)
+			return err
+		})
+		if err != nil && reserveCtx.Err() != nil {
+			// Outcome is unknown, the slot stays pending in the summary.
+			w.notifier.Notify(reserveCtx, notify.EventReservationFailed,
+				fmt.Sprintf("Reservation of %s at %s was interrupted, its outcome is unknown, check the portal", slot.Date, queue.Localization))
+			return result, unknownOutcomeError{err: err}
+		}
+		if err != nil && outcomeUnknown(err) {
+			w.notifier.Notify(reserveCtx, notify.EventReservationFailed,
+				fmt.Sprintf("Reservation of %s at %s failed, but the portal may have made it, the proceeding is checked before reserving again: %v", slot.Date, queue.Localization, err))
+			return result, unknownOutcomeError{err: err}
+		}
+
+		if err == nil {
+			return result, nil
+		}
+		var forbiddenError modelerrors.ForbiddenError
+		if !errors.As(err, &forbiddenError) || ctx.Err() != nil || !time.Now().Before(forbiddenRetryDeadline) {
+			w.reservationFinished(reserveCtx, target, queue, slot, result, err)
+			return result, err
+		}
+
+		output.Println(ctx)
+		output.Printf(ctx, "RequestPipeline, reservation of %s is forbidden, refreshing cookies and trying the same slot again...\n", slot.Date)
+		refreshCtx, cancelRefresh := context.WithDeadline(reserveCtx, forbiddenRetryDeadline)
+		refreshErr := s.refreshCookies(refreshCtx)
+		cancelRefresh()
+		if refreshErr != nil {
+			output.Printf(ctx, "RequestPipeline error during refreshing cookies: %v\n", refreshErr)
+			w.reservationFinished(reserveCtx, target, queue, slot, result, err)
+			return result, err
+		}
+	}
+}
+
+// unknownOutcomeError means the reservation request failed in a way
+// the portal may still have made the reservation.
+type unknownOutcomeError struct {
+	err error
+}
+
+func (e unknownOutcomeError) Error() string {
+	return fmt.Sprintf("outcome of the reservation is unknown: %v", e.err)
+}
+
+func (e unknownOutcomeError) Unwrap() error {
+	return e.err
+}
+
+// outcomeUnknown tells whether the failed reservation request could have
+// reached the portal and been applied: the connection broke after it was
+// sent or the portal failed itself.
+func outcomeUnknown(err error) bool {
+	var portalError modelerrors.PortalError
+	if errors.As(err, &portalError) {
+		// Not retryable server errors of the reservation may have been applied.
+		return !portalError.Retryable && portalError.StatusCode >= http.StatusInternalServerError
+	}
+	var urlError *url.Error
+	var budgetExhaustedError modelerrors.BudgetExhaustedError
+	return errors.As(err, &urlError) && !isConnectError(err) && !errors.As(err, &budgetExhaustedError)
+}
+
+// dryRunReservation prints the reservation request instead of sending it.
+func (w *watcher) dryRunReservation(ctx context.Context, target watchTarget, candidate selection.Candidate) {
+	output.Println(ctx, "🧪 RequestPipeline, dry run, the reservation request is not sent:")
+	output.Printf(ctx, "POST %s\n", w.session.client.ReserveAppointmentRequestUrl(candidate.Queue.ID))
+	printData(ctx, reserve.NewReservePayload(target.proceedingData, candidate.Slot))
+	w.summary.stepDone("Dry run: slot %s at %s would be reserved for proceeding %s", candidate.Slot.Date, candidate.Queue.Localization, target.proceedingData.ID)
+}
+
+// rescheduled reports the old and the new appointment of the target.
+func (w *watcher) rescheduled(ctx context.Context, target watchTarget, candidate selection.Candidate) {
+	oldAppointment := target.currentAppointment.In(selection.Warsaw).Format(selection.AppointmentLayout)
+	newAppointment := candidate.Time.Format(selection.AppointmentLayout)
+	earlierBy := target.currentAppointment.Sub(candidate.Time).Round(time.Minute)
+	output.Printf(ctx, "✅ RequestPipeline, proceeding %s rescheduled: old appointment %s, new appointment %s at %s, %s earlier.\n",
+		target.proceedingData.ID, oldAppointment, newAppointment, candidate.Queue.Localization, earlierBy)
+	w.summary.stepDone("Rescheduled proceeding %s from %s to %s at %s", target.proceedingData.ID, oldAppointment, newAppointment, candidate.Queue.Localization)
+}
+
+func (w *watcher) reservationFinished(ctx context.Context, target watchTarget, queue models.ReservationQueue, slot models.Slot, result models.ReservationResult, err error) {
+	w.summary.reservationFinished(slot, err == nil)
+	if err == nil {
+		message := fmt.Sprintf("Slot %s at %s is reserved for proceeding %s", slot.Date, queue.Localization, target.proceedingData.ID)
+		if result.ConfirmationID != "" {
+			message += fmt.Sprintf(", confirmation %s", result.ConfirmationID)
+		}
+		if target.rescheduling() {
+			message += fmt.Sprintf(", earlier than the current appointment %s, check whether it has to be cancelled",
+				target.currentAppointment.In(selection.Warsaw).Format(selection.AppointmentLayout))
+		}
+		w.notifier.Notify(ctx, notify.EventSlotReserved, message)
+		return
+	}
+	w.notifier.Notify(ctx, notify.EventReservationFailed,
+		fmt.Sprintf("Reservation of %s at %s failed: %v", slot.Date, queue.Localization, err))
+}
+
+// planLookups gets dates of all target queues and gives back a lookup for
+// every date matching the preferences, in the order of the selector.
+// A failure of one queue doesn't stop the search in others, unless it
+// concerns the whole session; the error is returned along with lookups
+// of other queues.
+func (w *watcher) planLookups(ctx context.Context, target watchTarget) ([]slotLookup, error) {
+	var lookups []slotLookup
+	var firstErr error
+	for queueRank, queue := range target.queues {
+		queueDates, err := w.getQueueDates(ctx, target.proceedingData, queue)
+		if err != nil {
+			if stopsWatch(ctx, err) {
+				return nil, err
+			}
+			output.Printf(ctx, "RequestPipeline, checking queue %s failed, trying next one: %v\n", queue.Localization, err)
+			firstErr = cmp.Or(firstErr, err)
+			continue
+		}
+		for _, queueDate := range queueDates {
+			if allowed, reason := selection.DateAllowed(queueDate, w.applicationData.Preferences); !allowed {
+				output.Printf(ctx, "RequestPipeline, skipping date %s at %s as %s.\n", queueDate, queue.Localization, reason)
+				continue
+			}
+			if target.rescheduling() {
+				// Dates were checked by DateAllowed, so they parse.
+				day, _ := time.ParseInLocation(time.DateOnly, queueDate, selection.Warsaw)
+				if !day.Before(target.bookBefore) {
+					output.Printf(ctx, "RequestPipeline, skipping date %s at %s as it doesn't beat the current appointment.\n", queueDate, queue.Localization)
+					continue
+				}
+			}
+			lookups = append(lookups, slotLookup{queue: queue, queueRank: queueRank, date: queueDate, bookBefore: target.bookBefore})
+		}
+	}
+	return orderLookups(w.slotSelector(), lookups), firstErr
+}
+
+// stopsWatch reports errors after which other queues aren't worth trying.
+func stopsWatch(ctx context.Context, err error) bool {
+	var unauthorizedError modelerrors.UnauthorizedError
+	var budgetExhaustedError modelerrors.BudgetExhaustedError
+	return ctx.Err() != nil || errors.As(err, &unauthorizedError) || errors.As(err, &budgetExhaustedError) || portalUnavailable(err)
+}
+
+// portalUnavailable tells whether the portal kept failing a request which
+// can be sent again after the transport retries, other queues and
+// proceedings would fail too.
+func portalUnavailable(err error) bool {
+	var portalError modelerrors.PortalError
+	if !errors.As(err, &portalError) || !portalError.Retryable {
+		return false
+	}
+	return errors.Is(err, modelerrors.ErrServerUnavailable) || errors.Is(err, modelerrors.ErrMaintenance) || errors.Is(err, modelerrors.ErrRateLimited)
+}
+
+// rejected tells whether the portal refused the reservation for a reason
+// other slots won't change.
+func rejected(err error) bool {
+	var invalidPersonDataError modelerrors.InvalidPersonDataError
+	var proceedingNotEligibleError modelerrors.ProceedingNotEligibleError
+	return errors.As(err, &invalidPersonDataError) || errors.As(err, &proceedingNotEligibleError)
+}
+
+func (w *watcher) getQueueDates(
+	ctx context.Context,
+	proceedingData *models.DetailedProceedingData,
+	queue models.ReservationQueue) ([]string, error) {
+	s := w.session
+	var queueDates []string
+	err := s.call(ctx, "getting queue dates", func() (err error) {
+		queueDates, err = dates.GetReservationQueueDates(ctx, s.client, proceedingData, queue)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("RequestPipeline error during getting queue dates: %w", err)
+	}
+	output.Printf(ctx, "Get queue dates for %s completed successfully, dates:\n", queue.ID)
+	printData(ctx, queueDates)
+	return queueDates, nil
+}
+
+// sleep pauses for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// randomPause is a short human-like delay between two consecutive requests.
+func randomPause() time.Duration {
+	return time.Duration(rand.Float64() * float64(time.Second))
+}
+
+// jitter spreads the interval by ±20% so checks don't happen on a fixed beat.
+func jitter(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return randomPause()
+	}
+	spread := float64(interval) * 0.2
+	return interval + time.Duration((rand.Float64()*2-1)*spread)
+}
@@ -0,0 +1,535 @@
+package requests
+
+import (
+	"bot-main/models"
+	modelerrors "bot-main/models/errors"
+	"bot-main/notify"
+	"bot-main/output"
+	"bot-main/requests/inpol"
+	"bot-main/selection"
+	test_utils "bot-main/tests/utils"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testWatchTarget = watchTarget{
+	proceedingData: &models.DetailedProceedingData{ID: "proc-1"},
+	queues:         []models.ReservationQueue{{ID: "queue-1"}},
+}
+
+func newTestWatcher(s *session, summary *Summary) *watcher {
+	return &watcher{
+		session: s,
+		summary: summary,
+		targets: []watchTarget{testWatchTarget},
+	}
+}
+
+func TestSleepStopsOnCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	started := time.Now()
+	err := sleep(ctx, time.Hour)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestReserveSlotCompletesAfterCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := test_utils.NewInpolClient(test_utils.NewTestClient(func(req *http.Request) *http.Response {
+		// Stop is requested while the reservation request is in flight.
+		cancel()
+		assert.NoError(t, req.Context().Err())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Body:       io.NopCloser(bytes.NewReader([]byte{})),
+		}
+	}), "tok")
+	s := newSession(client, models.ApplicationData{})
+	summary := NewSummary()
+	slot := models.Slot{ID: 42, Date: "2025-10-03T11:30:00", Count: 1}
+
+	_, err := newTestWatcher(s, summary).reserveSlot(ctx, testWatchTarget, testWatchTarget.queues[0], slot)
+	assert.NoError(t, err)
+	// Reserved once verified.
+	assert.Equal(t, []models.Slot{slot}, summary.pendingSlots)
+}
+
+func TestReserveSlotRefreshesCookiesWhenForbidden(t *testing.T) {
+	t.Parallel()
+
+	var signIns atomic.Int32
+	reserveCalls := 0
+	httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+		reserveCalls++
+		if reserveCalls == 1 {
+			return statusResponse(http.StatusForbidden)
+		}
+		return statusResponse(http.StatusOK)
+	})
+	jar, err := inpol.NewCookieJar()
+	assert.NoError(t, err)
+	httpClient.Jar = jar
+	staleCookieUrl, _ := url.Parse(test_utils.FakeBaseUrl)
+	jar.SetCookies(staleCookieUrl, []*http.Cookie{{Name: "stale", Value: "1"}})
+
+	s := newSession(test_utils.NewInpolClient(httpClient, "tok"), models.ApplicationData{ForbiddenRetryBudget: time.Minute})
+	summary := NewSummary()
+	slot := models.Slot{ID: 42, Date: "2025-10-03T11:30:00", Count: 1}
+
+	_, err = newTestWatcher(s, summary).reserveSlot(context.Background(), testWatchTarget, testWatchTarget.queues[0], slot)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, reserveCalls)
+	assert.Empty(t, jar.Cookies(staleCookieUrl))
+	assert.Equal(t, []models.Slot{slot}, summary.pendingSlots)
+}
+
+func TestReserveSlotForbiddenWithoutBudget(t *testing.T) {
+	t.Parallel()
+
+	var signIns atomic.Int32
+	httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+		return statusResponse(http.StatusForbidden)
+	})
+	s := newSession(test_utils.NewInpolClient(httpClient, "tok"), models.ApplicationData{})
+	summary := NewSummary()
+
+	_, err := newTestWatcher(s, summary).reserveSlot(context.Background(), testWatchTarget, testWatchTarget.queues[0], models.Slot{ID: 42})
+	assert.ErrorAs(t, err, &modelerrors.ForbiddenError{})
+	assert.Empty(t, summary.pendingSlots)
+	assert.Empty(t, summary.reservedSlots)
+}
+
+func TestWatchAndReserveFallsBackToNextQueue(t *testing.T) {
+	t.Parallel()
+
+	var signIns atomic.Int32
+	var reservedPath string
+	var timeline fakeTimeline
+	httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+		body := ""
+		switch req.URL.Path {
+		case "/api/proceedings/proc-1":
+			body = timeline.proceeding()
+		case "/api/reservations/queue/queue-1/dates":
+			body = `["2025-10-03"]`
+		case "/api/reservations/queue/queue-1/2025-10-03/slots":
+			body = `[{"id":1,"date":"2025-10-03T09:00:00","count":0}]`
+		case "/api/reservations/queue/queue-2/dates":
+			body = `["2025-10-04"]`
+		case "/api/reservations/queue/queue-2/2025-10-04/slots":
+			body = `[{"id":7,"date":"2025-10-04T10:15:00","count":1}]`
+		case "/api/reservations/queue/queue-2/reserve":
+			reservedPath = req.URL.Path
+			timeline.reserved("2025-10-04T10:15:00")
+		default:
+			return statusResponse(http.StatusNotFound)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+	})
+	s := newSession(test_utils.NewInpolClient(httpClient, "tok"), models.ApplicationData{})
+	summary := NewSummary()
+	w := &watcher{
+		session: s,
+		summary: summary,
+		targets: []watchTarget{{
+			proceedingData: &models.DetailedProceedingData{ID: "proc-1"},
+			queues:         []models.ReservationQueue{{ID: "queue-1"}, {ID: "queue-2"}},
+		}},
+	}
+
+	reservedSlots, err := w.watchAndReserve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Slot{{ID: 7, Date: "2025-10-04T10:15:00", Count: 1}}, reservedSlots)
+	assert.Equal(t, "/api/reservations/queue/queue-2/reserve", reservedPath)
+	assert.Equal(t, reservedSlots, summary.reservedSlots)
+}
+
+func TestWatchAndReserveDoesNotWaitForSlowLookups(t *testing.T) {
+	t.Parallel()
+
+	var signIns atomic.Int32
+	var inFlight, maxInFlight atomic.Int32
+	reserved := make(chan struct{})
+	var timeline fakeTimeline
+	httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+		body := ""
+		switch req.URL.Path {
+		case "/api/proceedings/proc-1":
+			body = timeline.proceeding()
+		case "/api/reservations/queue/queue-1/dates":
+			body = `["2025-10-02","2025-10-03","2025-10-06","2025-10-07"]`
+		case "/api/reservations/queue/queue-1/2025-10-02/slots":
+			// The earliest date answers only after the reservation was made.
+			select {
+			case <-reserved:
+			case <-req.Context().Done():
+			case <-time.After(10 * time.Second):
+			}
+			body = `[{"id":1,"date":"2025-10-02T09:00:00","count":1}]`
+		case "/api/reservations/queue/queue-1/2025-10-03/slots",
+			"/api/reservations/queue/queue-1/2025-10-06/slots",
+			"/api/reservations/queue/queue-1/2025-10-07/slots":
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				seen := maxInFlight.Load()
+				if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+					break
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			body = `[{"id":2,"date":"2025-10-03T10:00:00","count":1}]`
+		case "/api/reservations/queue/queue-1/reserve":
+			timeline.reserved("2025-10-03T10:00:00")
+			close(reserved)
+		default:
+			return statusResponse(http.StatusNotFound)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+	})
+	s := newSession(test_utils.NewInpolClient(httpClient, "tok"), models.ApplicationData{})
+	summary := NewSummary()
+	w := newTestWatcher(s, summary)
+	w.applicationData.LookupConcurrency = 2
+
+	started := time.Now()
+	reservedSlots, err := w.watchAndReserve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Slot{{ID: 2, Date: "2025-10-03T10:00:00", Count: 1}}, reservedSlots)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(1), "one of two workers is busy with the slow date")
+	assert.Less(t, time.Since(started), 10*time.Second)
+}
+
+func TestWatchTargetFallsBackToNextRankedSlot(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		maxAttempts   int
+		expectedSlot  models.Slot
+		expectedTries []string
+	}{
+		{
+			name:          "Next slot reserved",
+			maxAttempts:   3,
+			expectedSlot:  models.Slot{ID: 2, Date: "2025-10-03T10:00:00", Count: 1},
+			expectedTries: []string{`"slotId":1`, `"slotId":2`},
+		},
+		{
+			name:          "Attempts exhausted",
+			maxAttempts:   1,
+			expectedTries: []string{`"slotId":1`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var signIns atomic.Int32
+			var tries []string
+			var timeline fakeTimeline
+			httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+				body := ""
+				switch req.URL.Path {
+				case "/api/proceedings/proc-1":
+					body = timeline.proceeding()
+				case "/api/reservations/queue/queue-1/dates":
+					body = `["2025-10-03"]`
+				case "/api/reservations/queue/queue-1/2025-10-03/slots":
+					body = `[{"id":2,"date":"2025-10-03T10:00:00","count":1},{"id":1,"date":"2025-10-03T09:00:00","count":1}]`
+				case "/api/reservations/queue/queue-1/reserve":
+					payload, _ := io.ReadAll(req.Body)
+					tries = append(tries, string(payload))
+					if len(tries) == 1 {
+						return statusResponse(http.StatusConflict)
+					}
+					timeline.reserved("2025-10-03T10:00:00")
+				default:
+					return statusResponse(http.StatusNotFound)
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+			})
+			s := newSession(test_utils.NewInpolClient(httpClient, "tok"), models.ApplicationData{})
+			w := newTestWatcher(s, NewSummary())
+			w.applicationData.MaxReserveAttempts = tc.maxAttempts
+
+			slot, err := w.watchTarget(context.Background(), testWatchTarget)
+			if tc.expectedSlot.ID == 0 {
+				assert.ErrorAs(t, err, &modelerrors.SlotTakenError{})
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedSlot, slot)
+			assert.Len(t, tries, len(tc.expectedTries))
+			for i, expected := range tc.expectedTries {
+				assert.Contains(t, tries[i], expected)
+			}
+		})
+	}
+}
+
+func TestLookupSlotsRecoversPanic(t *testing.T) {
+	t.Parallel()
+
+	// Without a session the lookup dereferences nil.
+	w := &watcher{applicationData: models.ApplicationData{LookupConcurrency: 2}}
+	lookups := []slotLookup{{queue: models.ReservationQueue{ID: "queue-1"}, date: "2025-10-03"}}
+
+	var results []slotLookupResult
+	for result := range w.lookupSlots(context.Background(), testWatchTarget.proceedingData, lookups) {
+		results = append(results, result)
+	}
+	assert.Len(t, results, 1)
+	assert.ErrorContains(t, results[0].err, "panicked")
+	assert.Equal(t, lookups[0], results[0].lookup)
+}
+
+func TestWatchTargetDryRun(t *testing.T) {
+	t.Parallel()
+
+	var signIns atomic.Int32
+	var reserveCalls atomic.Int32
+	httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+		body := ""
+		switch req.URL.Path {
+		case "/api/proceedings/proc-1":
+			body = `{"id":"proc-1"}`
+		case "/api/reservations/queue/queue-1/dates":
+			body = `["2025-10-03"]`
+		case "/api/reservations/queue/queue-1/2025-10-03/slots":
+			body = `[{"id":7,"date":"2025-10-03T09:00:00","count":1}]`
+		case "/api/reservations/queue/queue-1/reserve":
+			reserveCalls.Add(1)
+		default:
+			return statusResponse(http.StatusNotFound)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+	})
+	s := newSession(test_utils.NewInpolClient(httpClient, "tok"), models.ApplicationData{})
+	summary := NewSummary()
+	w := newTestWatcher(s, summary)
+	w.applicationData.DryRun = true
+	var printed bytes.Buffer
+	ctx := output.WithWriter(context.Background(), output.NewSyncWriter(&printed))
+
+	slot, err := w.watchTarget(ctx, testWatchTarget)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, slot.ID)
+	assert.Equal(t, int32(0), reserveCalls.Load())
+	assert.Contains(t, printed.String(), "POST "+test_utils.FakeBaseUrl+"/api/reservations/queue/queue-1/reserve")
+	assert.Contains(t, printed.String(), `"slotId": 7`)
+	assert.Empty(t, summary.reservedSlots)
+}
+
+func TestWatchTargetReservationUnconfirmed(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		eventDate string
+		expected  string
+	}{
+		{name: "No event", expected: "no new AppointmentMade event"},
+		{name: "Other date", eventDate: "2025-10-03T11:00:00", expected: "is dated 2025-10-03T11:00:00+02:00 instead of 2025-10-03T09:00:00+02:00"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var signIns atomic.Int32
+			var timeline fakeTimeline
+			httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+				body := ""
+				switch req.URL.Path {
+				case "/api/proceedings/proc-1":
+					body = timeline.proceeding()
+				case "/api/reservations/queue/queue-1/dates":
+					body = `["2025-10-03"]`
+				case "/api/reservations/queue/queue-1/2025-10-03/slots":
+					body = `[{"id":1,"date":"2025-10-03T09:00:00","count":1}]`
+				case "/api/reservations/queue/queue-1/reserve":
+					if tc.eventDate != "" {
+						timeline.reserved(tc.eventDate)
+					}
+				default:
+					return statusResponse(http.StatusNotFound)
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+			})
+			s := newSession(test_utils.NewInpolClient(httpClient, "tok"), models.ApplicationData{})
+			summary := NewSummary()
+			w := newTestWatcher(s, summary)
+			eventsPath := filepath.Join(t.TempDir(), "events.jsonl")
+			w.notifier = notify.New("test", []models.NotificationSink{{Type: "file", Path: eventsPath}})
+
+			reservedSlots, err := w.watchAndReserve(context.Background())
+			assert.ErrorAs(t, err, &modelerrors.UnconfirmedReservationError{})
+			events, readErr := os.ReadFile(eventsPath)
+			assert.NoError(t, readErr)
+			assert.Contains(t, string(events), `"kind":"reservation_unconfirmed"`)
+			assert.NotContains(t, string(events), `"kind":"slot_reserved"`)
+			assert.ErrorContains(t, err, tc.expected)
+			assert.Empty(t, reservedSlots)
+			assert.Empty(t, summary.reservedSlots)
+			assert.Equal(t, []models.Slot{{ID: 1, Date: "2025-10-03T09:00:00", Count: 1}}, summary.unconfirmedSlots)
+		})
+	}
+}
+
+// fakeTimeline serves details of proc-1 with an AppointmentMade event
+// for every reserved slot.
+type fakeTimeline struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (f *fakeTimeline) reserved(slotDate string) {
+	slotTime, _ := selection.ParseSlotTime(slotDate)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, fmt.Sprintf(`{"eventType":"AppointmentMade","date":%q}`, slotTime.Format(time.RFC3339)))
+}
+
+func (f *fakeTimeline) proceeding() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return `{"id":"proc-1","timelineEvents":[` + strings.Join(f.events, ",") + `]}`
+}
+
+func TestWatchAndReserveStopsWhenReservationRejected(t *testing.T) {
+	t.Parallel()
+
+	var signIns atomic.Int32
+	var reserveCalls atomic.Int32
+	httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+		body := ""
+		switch req.URL.Path {
+		case "/api/proceedings/proc-1":
+			body = `{"id":"proc-1","canMakeAppointment":true}`
+		case "/api/reservations/queue/queue-1/dates":
+			body = `["2025-10-03"]`
+		case "/api/reservations/queue/queue-1/2025-10-03/slots":
+			body = `[{"id":1,"date":"2025-10-03T09:00:00","count":1},{"id":2,"date":"2025-10-03T10:00:00","count":1}]`
+		case "/api/reservations/queue/queue-1/reserve":
+			reserveCalls.Add(1)
+			return &http.Response{
+				StatusCode: http.StatusUnprocessableEntity,
+				Status:     "422 Unprocessable Entity",
+				Body:       io.NopCloser(bytes.NewReader([]byte(`{"message":"Invalid date of birth"}`))),
+			}
+		default:
+			return statusResponse(http.StatusNotFound)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+	})
+	s := newSession(test_utils.NewInpolClient(httpClient, "tok"), models.ApplicationData{})
+	summary := NewSummary()
+	w := newTestWatcher(s, summary)
+	w.applicationData.MaxReserveAttempts = 3
+
+	reservedSlots, err := w.watchAndReserve(context.Background())
+	var invalidPersonDataError modelerrors.InvalidPersonDataError
+	assert.ErrorAs(t, err, &invalidPersonDataError)
+	assert.Empty(t, reservedSlots)
+	// Other slots aren't tried, they would be rejected the same way.
+	assert.Equal(t, int32(1), reserveCalls.Load())
+	var printed strings.Builder
+	summary.Print(&printed)
+	assert.Contains(t, printed.String(), "Stopped watching proceeding proc-1, its reservation was rejected")
+}
+
+func TestWatchTargetStopsWhenPortalUnavailable(t *testing.T) {
+	t.Parallel()
+
+	var signIns atomic.Int32
+	var lookedUp sync.Map
+	httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+		lookedUp.Store(req.URL.Path, true)
+		return statusResponse(http.StatusServiceUnavailable)
+	})
+	s := newSession(test_utils.NewInpolClient(httpClient, "tok"), models.ApplicationData{})
+	target := testWatchTarget
+	target.queues = []models.ReservationQueue{{ID: "queue-1"}, {ID: "queue-2"}}
+
+	_, err := newTestWatcher(s, NewSummary()).watchTarget(context.Background(), target)
+	assert.ErrorIs(t, err, modelerrors.ErrServerUnavailable)
+	var portalError modelerrors.PortalError
+	assert.ErrorAs(t, err, &portalError)
+	assert.Equal(t, "dates", portalError.Endpoint)
+	// Other queues would fail the same way.
+	_, checked := lookedUp.Load("/api/reservations/queue/queue-2/dates")
+	assert.False(t, checked)
+}
+
+func TestWatchAndReserveChecksUnknownReservation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                 string
+		madeByFailedRequest  bool
+		expectedReserveCalls int32
+	}{
+		{name: "Made after all", madeByFailedRequest: true, expectedReserveCalls: 1},
+		{name: "Not made", expectedReserveCalls: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var signIns atomic.Int32
+			var reserveCalls atomic.Int32
+			var timeline fakeTimeline
+			httpClient := fakePortal(&signIns, func(req *http.Request) *http.Response {
+				body := ""
+				switch req.URL.Path {
+				case "/api/proceedings/proc-1":
+					body = timeline.proceeding()
+				case "/api/reservations/queue/queue-1/dates":
+					body = `["2025-10-03"]`
+				case "/api/reservations/queue/queue-1/2025-10-03/slots":
+					body = `[{"id":1,"date":"2025-10-03T09:00:00","count":1}]`
+				case "/api/reservations/queue/queue-1/reserve":
+					if reserveCalls.Add(1) == 1 {
+						if tc.madeByFailedRequest {
+							timeline.reserved("2025-10-03T09:00:00")
+						}
+						return statusResponse(http.StatusBadGateway)
+					}
+					timeline.reserved("2025-10-03T09:00:00")
+				default:
+					return statusResponse(http.StatusNotFound)
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(body)))}
+			})
+			s := newSession(test_utils.NewInpolClient(httpClient, "tok"), models.ApplicationData{})
+			summary := NewSummary()
+
+			reservedSlots, err := newTestWatcher(s, summary).watchAndReserve(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, []models.Slot{{ID: 1, Date: "2025-10-03T09:00:00", Count: 1}}, reservedSlots)
+			assert.Equal(t, tc.expectedReserveCalls, reserveCalls.Load())
+			assert.Empty(t, summary.pendingSlots)
+			assert.Equal(t, reservedSlots, summary.reservedSlots)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package selection
+
+import (
+	"bot-main/models"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DateAllowed checks a queue date in 2006-01-02 form against the date window,
+// allowed weekdays, blackout dates and public holidays. When the date is
+// not allowed the reason says why.
+func DateAllowed(date string, preferences models.SlotPreferences) (bool, string) {
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return false, fmt.Sprintf("date %q has unknown format", date)
+	}
+	// ISO dates compare correctly as strings.
+	if preferences.EarliestDate != "" && date < preferences.EarliestDate {
+		return false, "it's before " + preferences.EarliestDate
+	}
+	if preferences.LatestDate != "" && date > preferences.LatestDate {
+		return false, "it's after " + preferences.LatestDate
+	}
+	if len(preferences.Weekdays) > 0 && !slices.Contains(preferences.Weekdays, day.Weekday()) {
+		return false, "it's " + day.Weekday().String()
+	}
+	if slices.Contains(preferences.BlackoutDates, date) {
+		return false, "it's a blackout date"
+	}
+	if preferences.SkipPublicHolidays {
+		if name, ok := PolishHoliday(day); ok {
+			return false, "it's a public holiday (" + name + ")"
+		}
+	}
+	return true, ""
+}
+
+// ParseWeekday understands English weekday names and their three letter
+// abbreviations, case-insensitively.
+func ParseWeekday(value string) (time.Weekday, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		if value == name || value == name[:3] {
+			return weekday, nil
+		}
+	}
+	return 0, fmt.Errorf("%q is not a weekday", value)
+}
@@ -0,0 +1,108 @@
+package selection
+
+import (
+	"bot-main/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolishHoliday(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		date     string
+		expected string
+	}{
+		{date: "2025-01-01", expected: "New Year's Day"},
+		{date: "2025-04-21", expected: "Easter Monday"},
+		{date: "2026-04-06", expected: "Easter Monday"},
+		{date: "2024-04-01", expected: "Easter Monday"},
+		{date: "2025-06-08", expected: "Pentecost Sunday"},
+		{date: "2025-06-19", expected: "Corpus Christi"},
+		{date: "2026-06-04", expected: "Corpus Christi"},
+		{date: "2025-11-11", expected: "Independence Day"},
+		{date: "2025-12-24", expected: "Christmas Eve"},
+		{date: "2024-12-24"},
+		{date: "2025-04-22"},
+		{date: "2025-10-03"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.date, func(t *testing.T) {
+			t.Parallel()
+			date, err := time.Parse(time.DateOnly, tc.date)
+			assert.NoError(t, err)
+			name, ok := PolishHoliday(date)
+			assert.Equal(t, tc.expected != "", ok)
+			assert.Equal(t, tc.expected, name)
+		})
+	}
+}
+
+func TestEasterSunday(t *testing.T) {
+	t.Parallel()
+
+	expected := map[int]string{
+		2000: "2000-04-23",
+		2019: "2019-04-21",
+		2024: "2024-03-31",
+		2025: "2025-04-20",
+		2026: "2026-04-05",
+		2038: "2038-04-25",
+	}
+	for year, date := range expected {
+		assert.Equal(t, date, easterSunday(year).Format(time.DateOnly))
+	}
+}
+
+func TestDateAllowed(t *testing.T) {
+	t.Parallel()
+
+	preferences := models.SlotPreferences{
+		EarliestDate:       "2025-10-01",
+		LatestDate:         "2025-11-30",
+		Weekdays:           []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		BlackoutDates:      []string{"2025-10-10"},
+		SkipPublicHolidays: true,
+	}
+	testCases := []struct {
+		name           string
+		preferences    models.SlotPreferences
+		date           string
+		expected       bool
+		expectedReason string
+	}{
+		{name: "No preferences", date: "2025-12-25", expected: true},
+		{name: "Allowed", preferences: preferences, date: "2025-10-03", expected: true},
+		{name: "Window bounds are inclusive", preferences: preferences, date: "2025-10-01", expected: true},
+		{name: "Too early", preferences: preferences, date: "2025-09-30", expectedReason: "it's before 2025-10-01"},
+		{name: "Too late", preferences: preferences, date: "2025-12-01", expectedReason: "it's after 2025-11-30"},
+		{name: "Weekend", preferences: preferences, date: "2025-10-04", expectedReason: "it's Saturday"},
+		{name: "Blackout date", preferences: preferences, date: "2025-10-10", expectedReason: "it's a blackout date"},
+		{name: "Public holiday", preferences: preferences, date: "2025-11-11", expectedReason: "it's a public holiday (Independence Day)"},
+		{name: "Unknown format", date: "03.10.2025", expectedReason: `date "03.10.2025" has unknown format`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			allowed, reason := DateAllowed(tc.date, tc.preferences)
+			assert.Equal(t, tc.expected, allowed)
+			assert.Equal(t, tc.expectedReason, reason)
+		})
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	t.Parallel()
+
+	for value, expected := range map[string]time.Weekday{"mon": time.Monday, "Sunday": time.Sunday, " SAT ": time.Saturday} {
+		weekday, err := ParseWeekday(value)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, weekday)
+	}
+	_, err := ParseWeekday("pon")
+	assert.Error(t, err)
+}
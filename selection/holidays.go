@@ -0,0 +1,58 @@
+package selection
+
+import "time"
+
+// PolishHoliday returns the name of the Polish public holiday falling on the
+// date, offices are closed on these days.
+func PolishHoliday(date time.Time) (string, bool) {
+	year, month, day := date.Date()
+	name, ok := polishHolidays(year)[time.Date(year, month, day, 0, 0, 0, 0, time.UTC)]
+	return name, ok
+}
+
+func polishHolidays(year int) map[time.Time]string {
+	on := func(month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	easter := easterSunday(year)
+	holidays := map[time.Time]string{
+		on(time.January, 1):      "New Year's Day",
+		on(time.January, 6):      "Epiphany",
+		easter:                   "Easter Sunday",
+		easter.AddDate(0, 0, 1):  "Easter Monday",
+		on(time.May, 1):          "Labour Day",
+		on(time.May, 3):          "Constitution Day",
+		easter.AddDate(0, 0, 49): "Pentecost Sunday",
+		easter.AddDate(0, 0, 60): "Corpus Christi",
+		on(time.August, 15):      "Assumption Day",
+		on(time.November, 1):     "All Saints' Day",
+		on(time.November, 11):    "Independence Day",
+		on(time.December, 25):    "Christmas Day",
+		on(time.December, 26):    "Second Day of Christmas",
+	}
+	// Christmas Eve is a public holiday since 2025.
+	if year >= 2025 {
+		holidays[on(time.December, 24)] = "Christmas Eve"
+	}
+	return holidays
+}
+
+// easterSunday computes the Gregorian Easter date with the anonymous
+// (Meeus/Jones/Butcher) algorithm.
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
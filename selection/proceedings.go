@@ -0,0 +1,86 @@
+package selection
+
+import (
+	"bot-main/models"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseProceedingSelector reads selectors written as "all", "id:<proceeding id>",
+// "signature:<signature>", "type:<type id or name>" or "index:<number>".
+func ParseProceedingSelector(value string) (models.ProceedingSelector, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return models.ProceedingSelector{}, nil
+	}
+	if strings.EqualFold(value, models.SelectAllProceedings) {
+		return models.ProceedingSelector{Kind: models.SelectAllProceedings}, nil
+	}
+	kind, selectorValue, found := strings.Cut(value, ":")
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	selectorValue = strings.TrimSpace(selectorValue)
+	if !found || selectorValue == "" {
+		return models.ProceedingSelector{}, fmt.Errorf("Proceeding selector %q must look like kind:value or all", value)
+	}
+	switch kind {
+	case models.SelectProceedingByIndex:
+		if index, err := strconv.Atoi(selectorValue); err != nil || index < 0 {
+			return models.ProceedingSelector{}, fmt.Errorf("Proceeding selector %q has invalid index", value)
+		}
+	case models.SelectProceedingByID, models.SelectProceedingBySignature, models.SelectProceedingByType:
+	default:
+		return models.ProceedingSelector{}, fmt.Errorf("Proceeding selector %q has unknown kind %q", value, kind)
+	}
+	return models.ProceedingSelector{Kind: kind, Value: selectorValue}, nil
+}
+
+// SelectProceedings returns active proceedings matching the selector in the
+// order the portal returned them. An empty selector picks the one at index.
+func SelectProceedings(
+	proceedings []models.ActiveProceeding,
+	selector models.ProceedingSelector,
+	index int) ([]models.ActiveProceeding, error) {
+	if selector.Kind == "" {
+		selector = models.ProceedingSelector{Kind: models.SelectProceedingByIndex, Value: strconv.Itoa(index)}
+	}
+
+	var selected []models.ActiveProceeding
+	switch selector.Kind {
+	case models.SelectProceedingByIndex:
+		index, err := strconv.Atoi(selector.Value)
+		if err != nil || index < 0 || index >= len(proceedings) {
+			return nil, fmt.Errorf("❌ There is no active proceeding at index %s, %d proceeding(s) found", selector.Value, len(proceedings))
+		}
+		selected = append(selected, proceedings[index])
+	case models.SelectAllProceedings:
+		selected = append(selected, proceedings...)
+	case models.SelectProceedingByID, models.SelectProceedingBySignature, models.SelectProceedingByType:
+		for _, proceeding := range proceedings {
+			if proceedingMatches(proceeding, selector) {
+				selected = append(selected, proceeding)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Proceeding selector has unknown kind %q", selector.Kind)
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("❌ No active proceeding matches %s %q", selector.Kind, selector.Value)
+	}
+	return selected, nil
+}
+
+func proceedingMatches(proceeding models.ActiveProceeding, selector models.ProceedingSelector) bool {
+	switch selector.Kind {
+	case models.SelectProceedingByID:
+		return strings.EqualFold(proceeding.ProceedingsID, selector.Value)
+	case models.SelectProceedingBySignature:
+		return proceeding.Signature != nil && strings.EqualFold(strings.TrimSpace(*proceeding.Signature), selector.Value)
+	case models.SelectProceedingByType:
+		return strings.EqualFold(proceeding.Type.ID, selector.Value) ||
+			strings.EqualFold(proceeding.Type.English, selector.Value) ||
+			strings.EqualFold(proceeding.Type.Polish, selector.Value)
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package selection
+
+import (
+	"bot-main/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr(value string) *string {
+	return &value
+}
+
+var testProceedings = []models.ActiveProceeding{
+	{
+		ProceedingsID: "proc-1",
+		Signature:     ptr("WSC-II-P.6151.1.2025"),
+		Type:          models.ProceedingsType{ID: "type-temporary", English: "Temporary residence permit", Polish: "Zezwolenie na pobyt czasowy"},
+	},
+	{
+		ProceedingsID: "proc-2",
+		Type:          models.ProceedingsType{ID: "type-permanent", English: "Permanent residence permit"},
+	},
+	{
+		ProceedingsID: "proc-3",
+		Signature:     ptr("WSC-II-P.6151.3.2025"),
+		Type:          models.ProceedingsType{ID: "type-temporary", English: "Temporary residence permit"},
+	},
+}
+
+func TestSelectProceedings(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		selector      models.ProceedingSelector
+		index         int
+		expectedIDs   []string
+		expectedError bool
+	}{
+		{name: "Index by default", index: 1, expectedIDs: []string{"proc-2"}},
+		{name: "Index out of range", index: 3, expectedError: true},
+		{name: "Explicit index", selector: models.ProceedingSelector{Kind: models.SelectProceedingByIndex, Value: "2"}, expectedIDs: []string{"proc-3"}},
+		{name: "By ID", selector: models.ProceedingSelector{Kind: models.SelectProceedingByID, Value: "PROC-3"}, expectedIDs: []string{"proc-3"}},
+		{name: "By signature", selector: models.ProceedingSelector{Kind: models.SelectProceedingBySignature, Value: "wsc-ii-p.6151.1.2025"}, expectedIDs: []string{"proc-1"}},
+		{name: "By type ID", selector: models.ProceedingSelector{Kind: models.SelectProceedingByType, Value: "type-temporary"}, expectedIDs: []string{"proc-1", "proc-3"}},
+		{name: "By type English name", selector: models.ProceedingSelector{Kind: models.SelectProceedingByType, Value: "permanent residence permit"}, expectedIDs: []string{"proc-2"}},
+		{name: "By type Polish name", selector: models.ProceedingSelector{Kind: models.SelectProceedingByType, Value: "Zezwolenie na pobyt czasowy"}, expectedIDs: []string{"proc-1"}},
+		{name: "All", selector: models.ProceedingSelector{Kind: models.SelectAllProceedings}, expectedIDs: []string{"proc-1", "proc-2", "proc-3"}},
+		{name: "Nothing matches", selector: models.ProceedingSelector{Kind: models.SelectProceedingByID, Value: "proc-9"}, expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			selected, err := SelectProceedings(testProceedings, tc.selector, tc.index)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var ids []string
+			for _, proceeding := range selected {
+				ids = append(ids, proceeding.ProceedingsID)
+			}
+			assert.Equal(t, tc.expectedIDs, ids)
+		})
+	}
+}
+
+func TestParseProceedingSelector(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value         string
+		expected      models.ProceedingSelector
+		expectedError bool
+	}{
+		{value: "", expected: models.ProceedingSelector{}},
+		{value: "ALL", expected: models.ProceedingSelector{Kind: models.SelectAllProceedings}},
+		{value: "id:proc-1", expected: models.ProceedingSelector{Kind: models.SelectProceedingByID, Value: "proc-1"}},
+		{value: "Signature: WSC-II-P.6151.1.2025", expected: models.ProceedingSelector{Kind: models.SelectProceedingBySignature, Value: "WSC-II-P.6151.1.2025"}},
+		{value: "type:Temporary residence permit", expected: models.ProceedingSelector{Kind: models.SelectProceedingByType, Value: "Temporary residence permit"}},
+		{value: "index:2", expected: models.ProceedingSelector{Kind: models.SelectProceedingByIndex, Value: "2"}},
+		{value: "index:-1", expectedError: true},
+		{value: "proc-1", expectedError: true},
+		{value: "id:", expectedError: true},
+		{value: "name:proc-1", expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+			selector, err := ParseProceedingSelector(tc.value)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, selector)
+		})
+	}
+}
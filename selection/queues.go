@@ -0,0 +1,68 @@
+package selection
+
+import (
+	"bot-main/models"
+	"fmt"
+	"strings"
+)
+
+// SelectQueues orders reservation queues by the preference list. A preference
+// matches a queue by its localization, prefix, ID or any translated name,
+// case-insensitively. Without preferences the queue at index is used.
+func SelectQueues(
+	queues []models.ReservationQueue,
+	preferences []string,
+	index int) ([]models.ReservationQueue, error) {
+	if len(queues) == 0 {
+		return nil, fmt.Errorf("no reservation queues are offered")
+	}
+	if len(preferences) == 0 {
+		if index < 0 || index >= len(queues) {
+			return nil, fmt.Errorf("queues count and index incompatibility: %d and %d", len(queues), index)
+		}
+		return []models.ReservationQueue{queues[index]}, nil
+	}
+
+	var selected []models.ReservationQueue
+	taken := make(map[string]bool)
+	for _, preference := range preferences {
+		for _, queue := range queues {
+			if !taken[queue.ID] && queueMatches(queue, preference) {
+				taken[queue.ID] = true
+				selected = append(selected, queue)
+			}
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("none of configured queues (%s) is offered, offered are: %s",
+			strings.Join(preferences, ", "), DescribeQueues(queues))
+	}
+	return selected, nil
+}
+
+// DescribeQueues lists queues as "localization (prefix)".
+func DescribeQueues(queues []models.ReservationQueue) string {
+	descriptions := make([]string, 0, len(queues))
+	for _, queue := range queues {
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s)", queue.Localization, queue.Prefix))
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+func queueMatches(queue models.ReservationQueue, preference string) bool {
+	preference = strings.TrimSpace(preference)
+	for _, value := range []string{
+		queue.Localization,
+		queue.Prefix,
+		queue.ID,
+		queue.Polish,
+		queue.English,
+		queue.Russian,
+		queue.Ukrainian,
+	} {
+		if value != "" && strings.EqualFold(strings.TrimSpace(value), preference) {
+			return true
+		}
+	}
+	return false
+}
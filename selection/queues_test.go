@@ -0,0 +1,64 @@
+package selection
+
+import (
+	"bot-main/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testQueues = []models.ReservationQueue{
+	{ID: "queue-1", Localization: "Marszałkowska 3/5", Prefix: "M", English: "Residence card collection", Polish: "Odbiór karty pobytu"},
+	{ID: "queue-2", Localization: "Okopowa 7", Prefix: "O", English: "Fingerprints"},
+	{ID: "queue-3", Localization: "Marszałkowska 3/5", Prefix: "MF", English: "Fingerprints"},
+}
+
+func TestSelectQueues(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		queues        []models.ReservationQueue
+		preferences   []string
+		index         int
+		expectedIDs   []string
+		expectedError string
+	}{
+		{name: "Index by default", queues: testQueues, index: 1, expectedIDs: []string{"queue-2"}},
+		{name: "Index out of range", queues: testQueues, index: 3, expectedError: "queues count and index incompatibility: 3 and 3"},
+		{name: "No queues offered", expectedError: "no reservation queues are offered"},
+		{name: "By prefix", queues: testQueues, preferences: []string{"mf"}, expectedIDs: []string{"queue-3"}},
+		{name: "By ID", queues: testQueues, preferences: []string{"queue-2"}, expectedIDs: []string{"queue-2"}},
+		{name: "By Polish name", queues: testQueues, preferences: []string{"odbiór karty pobytu"}, expectedIDs: []string{"queue-1"}},
+		{name: "By name matching several queues", queues: testQueues, preferences: []string{"Fingerprints"}, expectedIDs: []string{"queue-2", "queue-3"}},
+		{
+			name:        "Preference order is kept without duplicates",
+			queues:      testQueues,
+			preferences: []string{"Okopowa 7", "marszałkowska 3/5", "Fingerprints"},
+			expectedIDs: []string{"queue-2", "queue-1", "queue-3"},
+		},
+		{
+			name:          "Configured queues are not offered",
+			queues:        testQueues,
+			preferences:   []string{"Legionowo"},
+			expectedError: "none of configured queues (Legionowo) is offered, offered are: Marszałkowska 3/5 (M), Okopowa 7 (O), Marszałkowska 3/5 (MF)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			selected, err := SelectQueues(tc.queues, tc.preferences, tc.index)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			var ids []string
+			for _, queue := range selected {
+				ids = append(ids, queue.ID)
+			}
+			assert.Equal(t, tc.expectedIDs, ids)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package selection
+
+import (
+	"bot-main/models"
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Candidate is a free slot which passed the preferences, together with
+// the queue it's in.
+type Candidate struct {
+	Queue models.ReservationQueue
+	// Position of the queue in the preference list, 0 is the most preferred.
+	QueueRank int
+	Slot      models.Slot
+	// Slot start in Warsaw time.
+	Time time.Time
+}
+
+// NewCandidate parses the slot date into the candidate time.
+func NewCandidate(queue models.ReservationQueue, queueRank int, slot models.Slot) (Candidate, error) {
+	slotTime, err := ParseSlotTime(slot.Date)
+	if err != nil {
+		return Candidate{}, err
+	}
+	return Candidate{Queue: queue, QueueRank: queueRank, Slot: slot, Time: slotTime}, nil
+}
+
+// SlotSelector decides which candidates are booked first.
+type SlotSelector interface {
+	// Rank returns the candidates ordered from the best one,
+	// the given slice is left untouched.
+	Rank(candidates []Candidate) []Candidate
+}
+
+// Built-in slot selection strategies.
+const (
+	StrategyEarliest = "earliest"
+	StrategyLatest   = "latest"
+	StrategyNearest  = "nearest"
+	StrategyWeighted = "weighted"
+)
+
+// NewSlotSelector creates the selector of the strategy, earliest by default.
+func NewSlotSelector(strategy models.SlotStrategy) (SlotSelector, error) {
+	var target time.Time
+	if strategy.TargetDate != "" {
+		var err error
+		target, err = time.ParseInLocation(time.DateOnly, strategy.TargetDate, Warsaw)
+		if err != nil {
+			return nil, fmt.Errorf("Slot strategy target date %q is not a YYYY-MM-DD date", strategy.TargetDate)
+		}
+	}
+	var preferredTime time.Duration
+	if strategy.PreferredTime != "" {
+		parsed, err := time.Parse("15:04", strategy.PreferredTime)
+		if err != nil {
+			return nil, fmt.Errorf("Slot strategy preferred time %q is not a HH:MM time", strategy.PreferredTime)
+		}
+		preferredTime = time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute
+	}
+
+	switch strings.ToLower(strategy.Name) {
+	case "", StrategyEarliest:
+		return EarliestSelector{}, nil
+	case StrategyLatest:
+		return LatestSelector{}, nil
+	case StrategyNearest:
+		if target.IsZero() {
+			return nil, fmt.Errorf("Slot strategy %s needs a target date", StrategyNearest)
+		}
+		return NearestSelector{Target: target}, nil
+	case StrategyWeighted:
+		if strategy.DateWeight < 0 || strategy.HourWeight < 0 || strategy.QueueWeight < 0 {
+			return nil, fmt.Errorf("Slot strategy %s weights must not be negative", StrategyWeighted)
+		}
+		return WeightedSelector{
+			Target:        target,
+			PreferredTime: preferredTime,
+			HasPreferred:  strategy.PreferredTime != "",
+			DateWeight:    strategy.DateWeight,
+			HourWeight:    strategy.HourWeight,
+			QueueWeight:   strategy.QueueWeight,
+		}, nil
+	}
+	return nil, fmt.Errorf("Slot strategy %q is unknown, use %s, %s, %s or %s",
+		strategy.Name, StrategyEarliest, StrategyLatest, StrategyNearest, StrategyWeighted)
+}
+
+// EarliestSelector books the soonest slot, the more preferred queue first on a tie.
+type EarliestSelector struct{}
+
+func (EarliestSelector) Rank(candidates []Candidate) []Candidate {
+	return rankBy(candidates, func(a, b Candidate) int {
+		return a.Time.Compare(b.Time)
+	})
+}
+
+// LatestSelector books the most distant slot, for those who need time to prepare.
+type LatestSelector struct{}
+
+func (LatestSelector) Rank(candidates []Candidate) []Candidate {
+	return rankBy(candidates, func(a, b Candidate) int {
+		return b.Time.Compare(a.Time)
+	})
+}
+
+// NearestSelector books the slot closest to the target date, in either direction.
+type NearestSelector struct {
+	Target time.Time
+}
+
+func (s NearestSelector) Rank(candidates []Candidate) []Candidate {
+	return rankBy(candidates, func(a, b Candidate) int {
+		return cmp.Compare(a.Time.Sub(s.Target).Abs(), b.Time.Sub(s.Target).Abs())
+	})
+}
+
+// WeightedSelector scores every candidate and books the lowest score:
+// DateWeight per day from the target date (the earliest candidate when
+// there is no target), HourWeight per hour from the preferred time of day
+// and QueueWeight per position of the queue in the preference list.
+type WeightedSelector struct {
+	Target        time.Time
+	PreferredTime time.Duration
+	HasPreferred  bool
+	DateWeight    float64
+	HourWeight    float64
+	QueueWeight   float64
+}
+
+func (s WeightedSelector) Rank(candidates []Candidate) []Candidate {
+	if len(candidates) == 0 {
+		return nil
+	}
+	reference := s.Target
+	if reference.IsZero() {
+		reference = slices.MinFunc(candidates, func(a, b Candidate) int {
+			return a.Time.Compare(b.Time)
+		}).Time
+	}
+	return rankBy(candidates, func(a, b Candidate) int {
+		return cmp.Compare(s.Score(a, reference), s.Score(b, reference))
+	})
+}
+
+// Score of the candidate, lower is better.
+func (s WeightedSelector) Score(candidate Candidate, reference time.Time) float64 {
+	score := s.DateWeight * math.Abs(candidate.Time.Sub(reference).Hours()) / 24
+	if s.HasPreferred {
+		// Wall clock time, adding it to midnight is an hour off on DST change days.
+		year, month, day := candidate.Time.In(Warsaw).Date()
+		hour, minute := int(s.PreferredTime/time.Hour), int(s.PreferredTime%time.Hour/time.Minute)
+		preferred := time.Date(year, month, day, hour, minute, 0, 0, Warsaw)
+		score += s.HourWeight * math.Abs(candidate.Time.Sub(preferred).Hours())
+	}
+	score += s.QueueWeight * float64(candidate.QueueRank)
+	return score
+}
+
+// rankBy sorts a copy of candidates, ties are broken by queue preference
+// and then by time, so the order doesn't depend on the order of lookups.
+func rankBy(candidates []Candidate, compare func(a, b Candidate) int) []Candidate {
+	ranked := slices.Clone(candidates)
+	slices.SortStableFunc(ranked, func(a, b Candidate) int {
+		return cmp.Or(
+			compare(a, b),
+			cmp.Compare(a.QueueRank, b.QueueRank),
+			a.Time.Compare(b.Time),
+			cmp.Compare(a.Slot.ID, b.Slot.ID),
+		)
+	})
+	return ranked
+}
@@ -0,0 +1,119 @@
+package selection
+
+import (
+	"bot-main/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlotSelectors(t *testing.T) {
+	t.Parallel()
+
+	candidates := []Candidate{
+		newTestCandidate(t, 1, 1, "2025-10-08T09:00:00"),
+		newTestCandidate(t, 2, 0, "2025-10-03T14:00:00"),
+		newTestCandidate(t, 3, 0, "2025-10-20T10:00:00"),
+		newTestCandidate(t, 4, 1, "2025-10-03T14:00:00"),
+	}
+
+	testCases := []struct {
+		name     string
+		strategy models.SlotStrategy
+		expected []int
+	}{
+		{name: "Default", strategy: models.SlotStrategy{}, expected: []int{2, 4, 1, 3}},
+		{name: "Earliest", strategy: models.SlotStrategy{Name: "Earliest"}, expected: []int{2, 4, 1, 3}},
+		{name: "Latest", strategy: models.SlotStrategy{Name: "latest"}, expected: []int{3, 1, 2, 4}},
+		{name: "Nearest", strategy: models.SlotStrategy{Name: "nearest", TargetDate: "2025-10-09"}, expected: []int{1, 2, 4, 3}},
+		{
+			name:     "Weighted by hour",
+			strategy: models.SlotStrategy{Name: "weighted", PreferredTime: "10:00", DateWeight: 0.1, HourWeight: 1},
+			expected: []int{1, 3, 2, 4},
+		},
+		{
+			name:     "Weighted by queue",
+			strategy: models.SlotStrategy{Name: "weighted", TargetDate: "2025-10-08", DateWeight: 1, QueueWeight: 10},
+			expected: []int{2, 1, 3, 4},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			selector, err := NewSlotSelector(tc.strategy)
+			assert.NoError(t, err)
+			var ranked []int
+			for _, candidate := range selector.Rank(candidates) {
+				ranked = append(ranked, candidate.Slot.ID)
+			}
+			assert.Equal(t, tc.expected, ranked)
+		})
+	}
+	assert.Equal(t, 1, candidates[0].Slot.ID, "given candidates must not be reordered")
+}
+
+func TestWeightedSelectorOnDSTChangeDays(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		day      string
+		expected []int
+	}{
+		{name: "Spring forward", day: "2025-03-30", expected: []int{2, 1}},
+		{name: "Fall back", day: "2025-10-26", expected: []int{2, 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			selector, err := NewSlotSelector(models.SlotStrategy{Name: "weighted", PreferredTime: "10:00", HourWeight: 1})
+			assert.NoError(t, err)
+			candidates := []Candidate{
+				newTestCandidate(t, 1, 0, tc.day+"T09:30:00"),
+				newTestCandidate(t, 2, 0, tc.day+"T10:15:00"),
+			}
+			var ranked []int
+			for _, candidate := range selector.Rank(candidates) {
+				ranked = append(ranked, candidate.Slot.ID)
+			}
+			assert.Equal(t, tc.expected, ranked)
+			weighted := selector.(WeightedSelector)
+			assert.InDelta(t, 0.25, weighted.Score(candidates[1], candidates[1].Time), 1e-9)
+		})
+	}
+}
+
+func TestNewSlotSelectorErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		strategy models.SlotStrategy
+	}{
+		{name: "Unknown", strategy: models.SlotStrategy{Name: "random"}},
+		{name: "Nearest without target", strategy: models.SlotStrategy{Name: "nearest"}},
+		{name: "Invalid target", strategy: models.SlotStrategy{Name: "nearest", TargetDate: "09.10.2025"}},
+		{name: "Invalid preferred time", strategy: models.SlotStrategy{Name: "weighted", PreferredTime: "25:00"}},
+		{name: "Negative weight", strategy: models.SlotStrategy{Name: "weighted", HourWeight: -1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			selector, err := NewSlotSelector(tc.strategy)
+			assert.Error(t, err)
+			assert.Nil(t, selector)
+		})
+	}
+}
+
+func newTestCandidate(t *testing.T, id, queueRank int, date string) Candidate {
+	t.Helper()
+	candidate, err := NewCandidate(models.ReservationQueue{ID: "queue"}, queueRank, models.Slot{ID: id, Date: date, Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return candidate
+}
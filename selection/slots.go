@@ -0,0 +1,169 @@
+package selection
+
+import (
+	"bot-main/models"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	// Embedded zone database, so Warsaw time works on hosts without one.
+	_ "time/tzdata"
+)
+
+// Warsaw is the timezone slot dates of the portal are in.
+var Warsaw = mustLoadLocation("Europe/Warsaw")
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+const slotDateLayout = "2006-01-02T15:04:05"
+
+// ParseSlotTime reads a slot date. Bare dates like 2025-10-03T14:25:00 are
+// Warsaw wall clock time, dates with an offset are converted to Warsaw time.
+// A wall clock time skipped by the spring DST change is moved forward by an
+// hour, a repeated autumn one is taken as the first of the two.
+func ParseSlotTime(date string) (time.Time, error) {
+	if slotTime, err := time.ParseInLocation(slotDateLayout, date, Warsaw); err == nil {
+		return normalizeDST(slotTime, date), nil
+	}
+	slotTime, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("slot date %q has unknown format", date)
+	}
+	return slotTime.In(Warsaw), nil
+}
+
+// AppointmentLayout is how an appointment time is given by the operator.
+const AppointmentLayout = "2006-01-02 15:04"
+
+// ParseAppointmentTime reads an appointment time in Warsaw time as
+// 2006-01-02 15:04, a date alone means the start of the day.
+func ParseAppointmentTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{AppointmentLayout, time.DateOnly} {
+		if appointment, err := time.ParseInLocation(layout, value, Warsaw); err == nil {
+			return appointment, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("appointment %q is not a YYYY-MM-DD HH:MM time", value)
+}
+
+// normalizeDST makes the choice the time package leaves open explicit.
+func normalizeDST(slotTime time.Time, date string) time.Time {
+	if slotTime.Format(slotDateLayout) != date {
+		// Skipped wall clock time, it must end up after the gap.
+		if slotTime.Format(slotDateLayout) < date {
+			slotTime = slotTime.Add(time.Hour)
+		}
+		return slotTime
+	}
+	if earlier := slotTime.Add(-time.Hour); earlier.Format(slotDateLayout) == date {
+		// Repeated wall clock time, the first occurrence is the one.
+		return earlier
+	}
+	return slotTime
+}
+
+// SlotAllowed checks the slot date and its Warsaw time of day against
+// the preferences: date filters, earliest and latest time and time windows.
+func SlotAllowed(slot models.Slot, preferences models.SlotPreferences) bool {
+	slotTime, err := ParseSlotTime(slot.Date)
+	if err != nil {
+		return false
+	}
+	if allowed, _ := DateAllowed(slotTime.Format(time.DateOnly), preferences); !allowed {
+		return false
+	}
+	timeOfDay := slotTime.Format("15:04")
+	if preferences.EarliestTime != "" && timeOfDay < preferences.EarliestTime {
+		return false
+	}
+	if preferences.LatestTime != "" && timeOfDay > preferences.LatestTime {
+		return false
+	}
+	return InTimeWindows(slotTime, preferences.TimeWindows)
+}
+
+// InTimeWindows reports whether the Warsaw time falls into any of the windows,
+// any time does when there are none. Window start is inclusive, end exclusive.
+func InTimeWindows(t time.Time, windows []models.TimeWindow) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	t = t.In(Warsaw)
+	timeOfDay := t.Format("15:04")
+	for _, window := range windows {
+		if len(window.Weekdays) > 0 && !slices.Contains(window.Weekdays, t.Weekday()) {
+			continue
+		}
+		if window.Start <= timeOfDay && timeOfDay < window.End {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTimeWindow reads windows like "Mon-Fri 09:00-12:00", "Sat 10:00-13:00",
+// "Mon,Wed 08:00-10:00" or just "09:00-12:00" for every day.
+// En dashes may be used instead of hyphens.
+func ParseTimeWindow(value string) (models.TimeWindow, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), "–", "-")
+	fields := strings.Fields(value)
+	var window models.TimeWindow
+	switch len(fields) {
+	case 1:
+	case 2:
+		weekdays, err := parseWeekdays(fields[0])
+		if err != nil {
+			return models.TimeWindow{}, fmt.Errorf("time window %q: %w", value, err)
+		}
+		window.Weekdays = weekdays
+	default:
+		return models.TimeWindow{}, fmt.Errorf("time window %q must look like \"Mon-Fri 09:00-12:00\"", value)
+	}
+
+	start, end, found := strings.Cut(fields[len(fields)-1], "-")
+	startTime, startErr := time.Parse("15:04", start)
+	endTime, endErr := time.Parse("15:04", end)
+	if !found || startErr != nil || endErr != nil {
+		return models.TimeWindow{}, fmt.Errorf("time window %q has invalid HH:MM-HH:MM hours", value)
+	}
+	if !startTime.Before(endTime) {
+		return models.TimeWindow{}, fmt.Errorf("time window %q ends before it starts", value)
+	}
+	window.Start = startTime.Format("15:04")
+	window.End = endTime.Format("15:04")
+	return window, nil
+}
+
+// parseWeekdays reads "Mon", "Mon-Fri", "Fri-Mon" or "Mon,Wed,Fri".
+func parseWeekdays(value string) ([]time.Weekday, error) {
+	var weekdays []time.Weekday
+	for _, part := range strings.Split(value, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := ParseWeekday(first)
+		if err != nil {
+			return nil, err
+		}
+		if !isRange {
+			weekdays = append(weekdays, from)
+			continue
+		}
+		to, err := ParseWeekday(last)
+		if err != nil {
+			return nil, err
+		}
+		for weekday := from; ; weekday = (weekday + 1) % 7 {
+			weekdays = append(weekdays, weekday)
+			if weekday == to {
+				break
+			}
+		}
+	}
+	return weekdays, nil
+}
@@ -0,0 +1,151 @@
+package selection
+
+import (
+	"bot-main/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSlotTime(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		date          string
+		expectedUTC   string
+		expectedLocal string
+		expectedError bool
+	}{
+		{date: "2025-10-03T14:25:00", expectedUTC: "2025-10-03T12:25:00Z", expectedLocal: "2025-10-03T14:25:00+02:00"},
+		{date: "2025-01-15T09:00:00", expectedUTC: "2025-01-15T08:00:00Z", expectedLocal: "2025-01-15T09:00:00+01:00"},
+		// Spring forward, 02:30 doesn't exist.
+		{date: "2025-03-30T02:30:00", expectedUTC: "2025-03-30T01:30:00Z", expectedLocal: "2025-03-30T03:30:00+02:00"},
+		// Fall back, 02:30 happens twice.
+		{date: "2025-10-26T02:30:00", expectedUTC: "2025-10-26T00:30:00Z", expectedLocal: "2025-10-26T02:30:00+02:00"},
+		{date: "2025-10-03T12:25:00Z", expectedUTC: "2025-10-03T12:25:00Z", expectedLocal: "2025-10-03T14:25:00+02:00"},
+		{date: "03.10.2025 14:25", expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.date, func(t *testing.T) {
+			t.Parallel()
+			slotTime, err := ParseSlotTime(tc.date)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedUTC, slotTime.UTC().Format(time.RFC3339))
+			assert.Equal(t, tc.expectedLocal, slotTime.Format(time.RFC3339))
+			assert.Equal(t, Warsaw, slotTime.Location())
+		})
+	}
+}
+
+func TestParseTimeWindow(t *testing.T) {
+	t.Parallel()
+
+	workdays := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	testCases := []struct {
+		value         string
+		expected      models.TimeWindow
+		expectedError bool
+	}{
+		{value: "Mon-Fri 09:00-12:00", expected: models.TimeWindow{Weekdays: workdays, Start: "09:00", End: "12:00"}},
+		{value: "Mon–Fri 09:00–12:00", expected: models.TimeWindow{Weekdays: workdays, Start: "09:00", End: "12:00"}},
+		{value: "sat 10:00-13:30", expected: models.TimeWindow{Weekdays: []time.Weekday{time.Saturday}, Start: "10:00", End: "13:30"}},
+		{value: "Fri-Mon 8:00-9:00", expected: models.TimeWindow{Weekdays: []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday}, Start: "08:00", End: "09:00"}},
+		{value: "Mon,Wed 08:00-10:00", expected: models.TimeWindow{Weekdays: []time.Weekday{time.Monday, time.Wednesday}, Start: "08:00", End: "10:00"}},
+		{value: "09:00-12:00", expected: models.TimeWindow{Start: "09:00", End: "12:00"}},
+		{value: "Mon-Fri 12:00-09:00", expectedError: true},
+		{value: "Mon-Fri 09:00", expectedError: true},
+		{value: "Pon-Pt 09:00-12:00", expectedError: true},
+		{value: "Mon-Fri 09:00-12:00 extra", expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+			window, err := ParseTimeWindow(tc.value)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, window)
+		})
+	}
+}
+
+func TestSlotAllowed(t *testing.T) {
+	t.Parallel()
+
+	weekdayMornings, err := ParseTimeWindow("Mon-Fri 09:00-12:00")
+	assert.NoError(t, err)
+	saturday, err := ParseTimeWindow("Sat 10:00-11:00")
+	assert.NoError(t, err)
+	withWindows := models.SlotPreferences{TimeWindows: []models.TimeWindow{weekdayMornings, saturday}}
+	withBounds := models.SlotPreferences{
+		EarliestDate: "2025-10-01",
+		LatestDate:   "2025-10-31",
+		EarliestTime: "09:00",
+		LatestTime:   "12:00",
+	}
+
+	testCases := []struct {
+		name        string
+		preferences models.SlotPreferences
+		slotDate    string
+		expected    bool
+	}{
+		{name: "No preferences", slotDate: "2025-10-05T18:00:00", expected: true},
+		{name: "Weekday morning", preferences: withWindows, slotDate: "2025-10-03T09:00:00", expected: true},
+		{name: "Window end is exclusive", preferences: withWindows, slotDate: "2025-10-03T12:00:00", expected: false},
+		{name: "Weekday afternoon", preferences: withWindows, slotDate: "2025-10-03T14:25:00", expected: false},
+		{name: "Saturday window", preferences: withWindows, slotDate: "2025-10-04T10:30:00", expected: true},
+		{name: "Saturday outside window", preferences: withWindows, slotDate: "2025-10-04T09:30:00", expected: false},
+		{name: "Sunday has no window", preferences: withWindows, slotDate: "2025-10-05T10:00:00", expected: false},
+		{name: "UTC slot in Warsaw window", preferences: withWindows, slotDate: "2025-10-03T07:30:00Z", expected: true},
+		{name: "Inside bounds", preferences: withBounds, slotDate: "2025-10-03T11:30:00", expected: true},
+		{name: "Latest time is inclusive", preferences: withBounds, slotDate: "2025-10-31T12:00:00", expected: true},
+		{name: "Date outside bounds", preferences: withBounds, slotDate: "2025-11-01T11:30:00", expected: false},
+		{name: "Time too early", preferences: withBounds, slotDate: "2025-10-03T08:59:00", expected: false},
+		{name: "Unknown format", slotDate: "soon", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, SlotAllowed(models.Slot{Date: tc.slotDate}, tc.preferences))
+		})
+	}
+}
+
+func TestParseAppointmentTime(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value         string
+		expected      string
+		expectedError bool
+	}{
+		{value: "2025-11-20 10:30", expected: "2025-11-20T10:30:00+01:00"},
+		{value: " 2025-07-01 08:00 ", expected: "2025-07-01T08:00:00+02:00"},
+		{value: "2025-11-20", expected: "2025-11-20T00:00:00+01:00"},
+		{value: "20.11.2025 10:30", expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+			appointment, err := ParseAppointmentTime(tc.value)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, appointment.Format(time.RFC3339))
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package test_utils
 
-import "net/http"
+import (
+	"bot-main/requests/inpol"
+	"net/http"
+)
+
+const FakeBaseUrl = "https://fake"
 
 type roundTripFunc func(req *http.Request) *http.Response
 
@@ -13,3 +18,14 @@ func NewTestClient(fn roundTripFunc) *http.Client {
 		Transport: fn,
 	}
 }
+
+// NewInpolClient wraps the test HTTP client into an inpol client pointing to
+// FakeBaseUrl. Nil HTTP client gives nil inpol client.
+func NewInpolClient(httpClient *http.Client, sessionToken string) *inpol.Client {
+	if httpClient == nil {
+		return nil
+	}
+	client := inpol.NewClient(httpClient, FakeBaseUrl)
+	client.SetToken(sessionToken)
+	return client
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"bot-main/config"
+	"bot-main/globalvars"
+	"bot-main/models"
+	"bot-main/selection"
+	"flag"
+	"fmt"
+)
+
+// ApplyConfigFile loads the file given by -config flag into globalvars.
+// Values of flags set explicitly on the command line are kept.
+func ApplyConfigFile() error {
+	if globalvars.ConfigPath == "" {
+		return nil
+	}
+	cfg, err := config.Load(globalvars.ConfigPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Configuration loaded from %s.\n", globalvars.ConfigPath)
+
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+	applyConfig(cfg, setFlags)
+	return nil
+}
+
+func applyConfig(cfg *config.Config, setFlags map[string]bool) {
+	applyString(&globalvars.Email, cfg.Account.Email, setFlags["email"])
+	applyString(&globalvars.Password, cfg.Account.Password, setFlags["password"])
+	applyString(&globalvars.PasswordFile, cfg.Account.PasswordFile, setFlags["password-file"])
+	applyString(&globalvars.Account, cfg.Account.Alias, setFlags["account"])
+	applyString(&globalvars.VaultFile, cfg.Account.VaultFile, setFlags["vault-file"])
+	applyInt(&globalvars.ProceedingsCheckIndex, cfg.Proceeding.Index, setFlags["proceedings-check-index"])
+	// Index given on the command line wins over a selector from the file.
+	applyString(&globalvars.Proceeding, cfg.Proceeding.Selector(), setFlags["proceeding"] || setFlags["proceedings-check-index"])
+	applyInt(&globalvars.QueueIndex, cfg.Queue.Index, setFlags["queue-index"])
+	if len(cfg.Queue.Preferred) > 0 && !setFlags["queue"] && !setFlags["queue-index"] {
+		globalvars.QueuePreferences = cfg.Queue.Preferred
+	}
+
+	if cfg.Polling.Interval > 0 && !setFlags["watch-interval"] {
+		globalvars.WatchInterval = cfg.Polling.Interval
+	}
+	applyInt(&globalvars.MaxRelogins, cfg.Polling.MaxRelogins, setFlags["max-relogins"])
+	applyInt(&globalvars.MaxReserveAttempts, cfg.Reservation.MaxAttempts, setFlags["max-reserve-attempts"])
+	if cfg.Reservation.AllowDoubleBooking && !setFlags["allow-double-booking"] {
+		globalvars.AllowDoubleBooking = true
+	}
+	if cfg.Reservation.DryRun && !setFlags["dry-run"] {
+		globalvars.DryRun = true
+	}
+	if cfg.Reservation.Reschedule.Enabled && !setFlags["reschedule"] {
+		globalvars.Reschedule = true
+	}
+	if cfg.Reservation.Reschedule.Margin != nil && !setFlags["reschedule-margin"] {
+		globalvars.RescheduleMargin = *cfg.Reservation.Reschedule.Margin
+	}
+	applyString(&globalvars.CurrentAppointment, cfg.Reservation.Reschedule.CurrentAppointment, setFlags["current-appointment"])
+	applyInt(&globalvars.LookupConcurrency, cfg.Polling.LookupConcurrency, setFlags["lookup-concurrency"])
+	if cfg.Polling.ForbiddenRetryBudget > 0 && !setFlags["forbidden-retry-budget"] {
+		globalvars.ForbiddenRetryBudget = cfg.Polling.ForbiddenRetryBudget
+	}
+
+	applyString(&globalvars.BaseUrl, cfg.HTTP.BaseUrl, setFlags["base-url"])
+	if cfg.HTTP.Timeout > 0 && !setFlags["http-timeout"] {
+		globalvars.HTTPTimeout = cfg.HTTP.Timeout
+	}
+	applyInt(&globalvars.DailyRequestBudget, cfg.HTTP.DailyRequestBudget, setFlags["daily-request-budget"])
+	applyString(&globalvars.BudgetFile, cfg.HTTP.BudgetFile, setFlags["budget-file"])
+	if len(cfg.HTTP.RateLimits) > 0 {
+		globalvars.RateLimits = make(map[string]models.RateLimit, len(cfg.HTTP.RateLimits))
+		for family, limit := range cfg.HTTP.RateLimits {
+			globalvars.RateLimits[family] = models.RateLimit{PerMinute: limit.PerMinute, Burst: limit.Burst}
+		}
+	}
+
+	globalvars.Preferences = models.SlotPreferences{
+		EarliestDate:       cfg.Preferences.EarliestDate,
+		LatestDate:         cfg.Preferences.LatestDate,
+		BlackoutDates:      cfg.Preferences.BlackoutDates,
+		SkipPublicHolidays: cfg.Preferences.SkipPublicHolidays == nil || *cfg.Preferences.SkipPublicHolidays,
+		EarliestTime:       cfg.Preferences.EarliestTime,
+		LatestTime:         cfg.Preferences.LatestTime,
+	}
+	for _, value := range cfg.Preferences.Weekdays {
+		// Weekdays were validated when the file was parsed.
+		weekday, _ := selection.ParseWeekday(value)
+		globalvars.Preferences.Weekdays = append(globalvars.Preferences.Weekdays, weekday)
+	}
+	for _, value := range cfg.Preferences.TimeWindows {
+		window, _ := selection.ParseTimeWindow(value)
+		globalvars.Preferences.TimeWindows = append(globalvars.Preferences.TimeWindows, window)
+	}
+	strategy := cfg.Strategy.SlotStrategy()
+	if setFlags["strategy"] {
+		strategy.Name = globalvars.SlotStrategy.Name
+	}
+	globalvars.SlotStrategy = strategy
+	globalvars.Accounts = nil
+	for _, account := range cfg.Accounts {
+		globalvars.Accounts = append(globalvars.Accounts, models.AccountSource{
+			Alias:        account.Alias,
+			Email:        account.Email,
+			Password:     account.Password,
+			PasswordFile: account.PasswordFile,
+		})
+	}
+	globalvars.Notifications = nil
+	for _, notification := range cfg.Notifications {
+		globalvars.Notifications = append(globalvars.Notifications, models.NotificationSink{
+			Type: notification.Type,
+			Url:  notification.Url,
+			Path: notification.Path,
+		})
+	}
+}
+
+func applyString(target *string, value string, flagSet bool) {
+	if value != "" && !flagSet {
+		*target = value
+	}
+}
+
+func applyInt(target *int, value *int, flagSet bool) {
+	if value != nil && !flagSet {
+		*target = *value
+	}
+}
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"bot-main/globalvars"
+	"bot-main/models"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Environment variables login data can be passed with.
+const (
+	EmailEnv        = "INPOL_EMAIL"
+	PasswordEnv     = "INPOL_PASSWORD"
+	PasswordFileEnv = "INPOL_PASSWORD_FILE"
+)
+
+// credentialSource is one place a credential can come from.
+// An empty value means the source doesn't provide it.
+type credentialSource struct {
+	name string
+	read func() (string, error)
+}
+
+func valueSource(name, value string, enabled bool) credentialSource {
+	return credentialSource{name: name, read: func() (string, error) {
+		if !enabled {
+			return "", nil
+		}
+		return value, nil
+	}}
+}
+
+func envSource(key string) credentialSource {
+	return credentialSource{name: key + " environment variable", read: func() (string, error) {
+		return os.Getenv(key), nil
+	}}
+}
+
+func fileSource(name, path string, enabled bool) credentialSource {
+	return credentialSource{name: name, read: func() (string, error) {
+		if !enabled || path == "" {
+			return "", nil
+		}
+		return readSecretFile(path)
+	}}
+}
+
+// resolveCredential returns the value of the first source providing it.
+func resolveCredential(sources []credentialSource) (value string, source string, err error) {
+	for _, s := range sources {
+		value, err = s.read()
+		if err != nil {
+			return "", "", fmt.Errorf("Error reading credential from %s: %w", s.name, err)
+		}
+		if value != "" {
+			return value, s.name, nil
+		}
+	}
+	return "", "", nil
+}
+
+// readSecretFile reads a Docker/Kubernetes style secret, the whole file is
+// the value, only the trailing line break is dropped.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return trimLineEnd(string(data)), nil
+}
+
+// trimLineEnd drops only the line ending, spaces around a secret are part of it.
+func trimLineEnd(value string) string {
+	return strings.TrimRight(value, "\r\n")
+}
+
+// MaskSecret hides a secret in logs, not even its length is revealed.
+func MaskSecret(secret string) string {
+	if secret == "" {
+		return "(empty)"
+	}
+	return "********"
+}
+
+type account struct {
+	name      string
+	loginData models.LoginData
+}
+
+// readRequiredAccounts resolves login data of every account: aliases given with
+// -account are loaded from the vault, otherwise accounts listed in the
+// configuration file are used and at last the single account of
+// ReadRequiredLoginData.
+func readRequiredAccounts() ([]account, error) {
+	if globalvars.Account != "" {
+		aliases := splitList(globalvars.Account)
+		loginData, err := readLoginDataFromVault(globalvars.VaultFile, aliases)
+		if err != nil {
+			return nil, err
+		}
+		accounts := make([]account, 0, len(aliases))
+		for i, alias := range aliases {
+			printLoginData(loginData[i], "vault "+globalvars.VaultFile+" account "+alias, "vault")
+			accounts = append(accounts, account{name: alias, loginData: loginData[i]})
+		}
+		return accounts, nil
+	}
+
+	if len(globalvars.Accounts) > 0 && globalvars.Email == "" {
+		return readConfiguredAccounts(globalvars.Accounts)
+	}
+
+	loginData, err := ReadRequiredLoginData()
+	if err != nil {
+		return nil, err
+	}
+	return []account{{name: loginData.Email, loginData: loginData}}, nil
+}
+
+// readConfiguredAccounts resolves accounts listed in the configuration file,
+// the vault is opened once for all aliases.
+func readConfiguredAccounts(sources []models.AccountSource) ([]account, error) {
+	accounts := make([]account, len(sources))
+	var aliases []string
+	var aliasIndexes []int
+	for i, source := range sources {
+		if source.Alias != "" {
+			aliases = append(aliases, source.Alias)
+			aliasIndexes = append(aliasIndexes, i)
+			continue
+		}
+		password, passwordSource, err := resolveCredential([]credentialSource{
+			fileSource("password_file", source.PasswordFile, true),
+			valueSource("configuration file", source.Password, true),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if password == "" {
+			password, err = ReadPasswordFromConsole(fmt.Sprintf("Enter password for %s: ", source.Email))
+			if err != nil {
+				return nil, err
+			}
+			passwordSource = "prompt"
+		}
+		loginData := models.LoginData{Email: source.Email, Password: password}
+		printLoginData(loginData, "configuration file", passwordSource)
+		accounts[i] = account{name: source.Email, loginData: loginData}
+	}
+
+	if len(aliases) > 0 {
+		loginData, err := readLoginDataFromVault(globalvars.VaultFile, aliases)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range aliasIndexes {
+			printLoginData(loginData[j], "vault "+globalvars.VaultFile+" account "+aliases[j], "vault")
+			accounts[i] = account{name: aliases[j], loginData: loginData[j]}
+		}
+	}
+	return accounts, nil
+}
+
+// ReadRequiredLoginData resolves login data of a single account, the first
+// source having a value wins: command line flag, environment variable,
+// secret file, configuration file and at last an interactive prompt.
+// Password typed into the prompt isn't echoed.
+func ReadRequiredLoginData() (models.LoginData, error) {
+	setFlags := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	email, emailSource, err := resolveCredential([]credentialSource{
+		valueSource("-email flag", globalvars.Email, setFlags["email"]),
+		envSource(EmailEnv),
+		valueSource("configuration file", globalvars.Email, !setFlags["email"]),
+	})
+	if err != nil {
+		return models.LoginData{}, err
+	}
+	if email == "" {
+		email, emailSource = ReadStringFromConsole("Enter email: "), "prompt"
+	}
+
+	password, passwordSource, err := resolveCredential([]credentialSource{
+		valueSource("-password flag", globalvars.Password, setFlags["password"]),
+		envSource(PasswordEnv),
+		fileSource("-password-file flag", globalvars.PasswordFile, setFlags["password-file"]),
+		fileSource(PasswordFileEnv+" environment variable", os.Getenv(PasswordFileEnv), true),
+		fileSource("configuration file password_file", globalvars.PasswordFile, !setFlags["password-file"]),
+		valueSource("configuration file", globalvars.Password, !setFlags["password"]),
+	})
+	if err != nil {
+		return models.LoginData{}, err
+	}
+	if password == "" {
+		password, err = ReadPasswordFromConsole("Enter password: ")
+		if err != nil {
+			return models.LoginData{}, err
+		}
+		passwordSource = "prompt"
+	}
+	if passwordSource == "-password flag" {
+		fmt.Printf("⚠️ Password passed with -password flag is visible to other users in process list and shell history, prefer %s or %s.\n",
+			PasswordEnv, PasswordFileEnv)
+	}
+
+	loginData := models.LoginData{
+		Email:    email,
+		Password: password,
+	}
+
+	printLoginData(loginData, emailSource, passwordSource)
+	return loginData, nil
+}
+
+// printLoginData prints the login data for check, the password masked.
+func printLoginData(loginData models.LoginData, emailSource, passwordSource string) {
+	fmt.Println("\n---")
+	fmt.Printf("✅ Login data saved.\n")
+	fmt.Printf("Email: %s [from %s]\n", loginData.Email, emailSource)
+	fmt.Printf("Password: %s [from %s]\n", MaskSecret(loginData.Password), passwordSource)
+	fmt.Println("---")
+}
+
+// ReadPasswordFromConsole reads a line without echoing it when stdin is a terminal.
+// Leading and trailing spaces are kept.
+func ReadPasswordFromConsole(message string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return trimLineEnd(readLineFromConsole(message)), nil
+	}
+	fmt.Print(message)
+	password, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("Error reading password from console: %w", err)
+	}
+	return trimLineEnd(string(password)), nil
+}
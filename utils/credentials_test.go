@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"bot-main/models"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveCredential(t *testing.T) {
+	t.Parallel()
+
+	secretPath := filepath.Join(t.TempDir(), "password")
+	assert.NoError(t, os.WriteFile(secretPath, []byte("from-file\n"), 0o600))
+
+	testCases := []struct {
+		name           string
+		sources        []credentialSource
+		expectedValue  string
+		expectedSource string
+		expectedError  bool
+	}{
+		{
+			name: "First source with value wins",
+			sources: []credentialSource{
+				valueSource("flag", "", true),
+				fileSource("file", secretPath, true),
+				valueSource("config", "from-config", true),
+			},
+			expectedValue:  "from-file",
+			expectedSource: "file",
+		},
+		{
+			name: "Disabled sources are skipped",
+			sources: []credentialSource{
+				valueSource("flag", "from-flag", false),
+				fileSource("file", secretPath, false),
+				valueSource("config", "from-config", true),
+			},
+			expectedValue:  "from-config",
+			expectedSource: "config",
+		},
+		{
+			name:    "No source has value",
+			sources: []credentialSource{valueSource("flag", "", true), fileSource("file", "", true)},
+		},
+		{
+			name:          "Missing secret file",
+			sources:       []credentialSource{fileSource("file", filepath.Join(t.TempDir(), "missing"), true)},
+			expectedError: true,
+		},
+		{
+			name: "Read error stops resolution",
+			sources: []credentialSource{
+				{name: "broken", read: func() (string, error) { return "", errors.New("boom") }},
+				valueSource("config", "from-config", true),
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			value, source, err := resolveCredential(tc.sources)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedValue, value)
+			assert.Equal(t, tc.expectedSource, source)
+		})
+	}
+}
+
+func TestReadSecretFileKeepsInnerWhitespace(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "password")
+	assert.NoError(t, os.WriteFile(path, []byte(" pass word \r\n"), 0o600))
+	value, err := readSecretFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, " pass word ", value)
+}
+
+func TestTrimLineEndKeepsSpaces(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, " pass word ", trimLineEnd(" pass word \r\n"))
+	assert.Equal(t, "\tpass", trimLineEnd("\tpass\n"))
+	assert.Equal(t, "pass ", trimLineEnd("pass "))
+}
+
+func TestPasswordIsMasked(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "********", MaskSecret("hunter2"))
+	assert.Equal(t, "(empty)", MaskSecret(""))
+
+	loginData := models.LoginData{Email: "user@example.com", Password: "hunter2"}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		assert.NotContains(t, fmt.Sprintf(format, loginData), "hunter2")
+	}
+	assert.NotContains(t, fmt.Sprintf("%+v", models.ApplicationData{LoginData: loginData}), "hunter2")
+}
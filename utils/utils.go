@@ -3,74 +3,132 @@ package utils
 import (
 	"bot-main/globalvars"
 	"bot-main/models"
+	"bot-main/selection"
 	"bufio"
 	"flag"
 	"fmt"
-	"net/http"
 	"os"
 	"strings"
 )
 
 func RegisterCommandLineArgs() {
-	flag.StringVar(&globalvars.Email, "email", "", "Login email for enter")
-	flag.StringVar(&globalvars.Password, "password", "", "Password for enter")
+	flag.StringVar(&globalvars.ConfigPath, "config", "", "Path to YAML configuration file, command line flags override its values")
+	flag.StringVar(&globalvars.Email, "email", "", "Login email for enter, "+EmailEnv+" can be used instead")
+	flag.StringVar(&globalvars.Password, "password", "", "Password for enter, visible in process list, prefer "+PasswordEnv+" or "+PasswordFileEnv)
+	flag.StringVar(&globalvars.Account, "account", "", "Aliases of accounts to load from the vault separated by commas, see \"vault\" command")
+	flag.StringVar(&globalvars.VaultFile, "vault-file", globalvars.VaultFile, "Encrypted vault file(by default vault.json)")
+	flag.StringVar(&globalvars.PasswordFile, "password-file", "", "File containing the password, e.g. a mounted Docker or Kubernetes secret")
 	flag.IntVar(&globalvars.ProceedingsCheckIndex, "proceedings-check-index", 0, "Proceedings check index for enter(by default 0)")
+	flag.StringVar(&globalvars.Proceeding, "proceeding", "", "Proceedings to watch instead of the index: all, id:<id>, signature:<signature> or type:<type id or English name>")
+	flag.IntVar(&globalvars.QueueIndex, "queue-index", 0, "Reservation queue index(by default 0)")
+	flag.Func("queue", "Reservation queues to watch in order of preference separated by commas, by localization, prefix, ID or name", func(value string) error {
+		globalvars.QueuePreferences = splitList(value)
+		return nil
+	})
+	flag.StringVar(&globalvars.SlotStrategy.Name, "strategy", "", "How free slots are ranked: earliest, latest, nearest or weighted, the last two need a target date in the config(by default earliest)")
+	flag.DurationVar(&globalvars.WatchInterval, "watch-interval", globalvars.WatchInterval, "Pause between slot checks in watch mode(by default 30s)")
+	flag.IntVar(&globalvars.LookupConcurrency, "lookup-concurrency", globalvars.LookupConcurrency, "How many date slots are looked up at once in watch mode(by default 4)")
+	flag.IntVar(&globalvars.MaxReserveAttempts, "max-reserve-attempts", globalvars.MaxReserveAttempts, "How many slots are tried in turn when they are already taken(by default 3)")
+	flag.BoolVar(&globalvars.AllowDoubleBooking, "allow-double-booking", false, "Book proceedings which already have an appointment, otherwise they are checked before reserving and every 10th watch attempt")
+	flag.BoolVar(&globalvars.DryRun, "dry-run", false, "Do everything but reserving, the reservation request is printed and the bot exits")
+	flag.BoolVar(&globalvars.Reschedule, "reschedule", false, "Only book slots earlier than the current appointment of the proceeding")
+	flag.DurationVar(&globalvars.RescheduleMargin, "reschedule-margin", globalvars.RescheduleMargin, "How much earlier than the current appointment a slot must start in reschedule mode(by default 24h)")
+	flag.StringVar(&globalvars.CurrentAppointment, "current-appointment", "", "Current appointment as \"YYYY-MM-DD HH:MM\" for reschedule mode, by default the date of its AppointmentMade event")
+	flag.IntVar(&globalvars.MaxRelogins, "max-relogins", globalvars.MaxRelogins, "Max consecutive re-logins after the session expired(by default 3)")
+	flag.DurationVar(&globalvars.ForbiddenRetryBudget, "forbidden-retry-budget", globalvars.ForbiddenRetryBudget, "How long a forbidden reservation is retried with refreshed cookies(by default 10s)")
+	flag.IntVar(&globalvars.DailyRequestBudget, "daily-request-budget", globalvars.DailyRequestBudget, "Max requests per day for the account, 0 disables the limit(by default 1500)")
+	flag.StringVar(&globalvars.BudgetFile, "budget-file", globalvars.BudgetFile, "File keeping daily request budget usage(by default budget.json)")
+	flag.StringVar(&globalvars.BaseUrl, "base-url", globalvars.BaseUrl, "Portal address(by default https://inpol.mazowieckie.pl)")
+	flag.DurationVar(&globalvars.HTTPTimeout, "http-timeout", globalvars.HTTPTimeout, "Timeout of a single HTTP request(by default 1m)")
 	flag.Parse()
 }
 
-func ReadRequiredApplicationData() models.ApplicationData {
-	return models.ApplicationData{
-		LoginData:             ReadRequiredLoginData(),
-		ProceedingsCheckIndex: globalvars.ProceedingsCheckIndex,
+// ReadRequiredApplicationData returns data of every account the bot should run for.
+func ReadRequiredApplicationData() ([]models.ApplicationData, error) {
+	proceedingSelector, err := selection.ParseProceedingSelector(globalvars.Proceeding)
+	if err != nil {
+		return nil, err
 	}
-}
-
-func ReadRequiredLoginData() models.LoginData {
-	// Checking if data was entered. If not, request it.
-	if globalvars.Email == "" {
-		globalvars.Email = ReadStringFromConsole("Enter email: ")
+	if globalvars.LookupConcurrency < 1 {
+		return nil, fmt.Errorf("Lookup concurrency must be at least 1")
 	}
-
-	if globalvars.Password == "" {
-		globalvars.Password = ReadStringFromConsole("Enter password: ")
+	if globalvars.MaxReserveAttempts < 1 {
+		return nil, fmt.Errorf("Max reserve attempts must be at least 1")
+	}
+	if globalvars.RescheduleMargin < 0 {
+		return nil, fmt.Errorf("Reschedule margin must not be negative")
+	}
+	if globalvars.CurrentAppointment != "" {
+		if _, err = selection.ParseAppointmentTime(globalvars.CurrentAppointment); err != nil {
+			return nil, fmt.Errorf("Current appointment: %w", err)
+		}
+	}
+	if _, err = selection.NewSlotSelector(globalvars.SlotStrategy); err != nil {
+		return nil, err
+	}
+	accounts, err := readRequiredAccounts()
+	if err != nil {
+		return nil, err
 	}
+	applicationData := make([]models.ApplicationData, 0, len(accounts))
+	for _, account := range accounts {
+		data := newApplicationData()
+		data.AccountName = account.name
+		data.LoginData = account.loginData
+		data.ProceedingSelector = proceedingSelector
+		applicationData = append(applicationData, data)
+	}
+	return applicationData, nil
+}
 
-	// Printing the entered data for check
-	fmt.Println("\n---")
-	fmt.Printf("✅ Login data saved.\n")
-	fmt.Printf("Email: %s\n", globalvars.Email)
-	fmt.Printf("Password: %s [Length: %d]\n", globalvars.Password, len(globalvars.Password))
-	fmt.Println("---")
+func newApplicationData() models.ApplicationData {
+	return models.ApplicationData{
+		ProceedingsCheckIndex:  globalvars.ProceedingsCheckIndex,
+		QueueIndex:             globalvars.QueueIndex,
+		QueuePreferences:       globalvars.QueuePreferences,
+		Preferences:            globalvars.Preferences,
+		SlotStrategy:           globalvars.SlotStrategy,
+		Notifications:          globalvars.Notifications,
+		WatchInterval:          globalvars.WatchInterval,
+		LookupConcurrency:      globalvars.LookupConcurrency,
+		MaxReserveAttempts:     globalvars.MaxReserveAttempts,
+		AllowDoubleBooking:     globalvars.AllowDoubleBooking,
+		Reschedule:             globalvars.Reschedule,
+		DryRun:                 globalvars.DryRun,
+		RescheduleMargin:       globalvars.RescheduleMargin,
+		CurrentAppointment:     globalvars.CurrentAppointment,
+		MaxConsecutiveRelogins: globalvars.MaxRelogins,
+		ForbiddenRetryBudget:   globalvars.ForbiddenRetryBudget,
+		DailyRequestBudget:     globalvars.DailyRequestBudget,
+		BudgetFile:             globalvars.BudgetFile,
+		RateLimits:             globalvars.RateLimits,
+		BaseUrl:                globalvars.BaseUrl,
+		HTTPTimeout:            globalvars.HTTPTimeout,
+	}
+}
 
-	return models.LoginData{
-		Email:    globalvars.Email,
-		Password: globalvars.Password,
+// splitList splits comma separated values, dropping empty ones.
+func splitList(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
 	}
+	return values
 }
 
+// stdinReader is shared, a reader per call would swallow buffered lines of the next prompts.
+var stdinReader = bufio.NewReader(os.Stdin)
+
 func ReadStringFromConsole(message string) string {
-	fmt.Print(message)
-	var result string
-	reader := bufio.NewReader(os.Stdin)
-	// Reading a line from standard input
-	result, _ = reader.ReadString('\n')
-	// Remove any trailing newline characters
-	result = strings.TrimSpace(result)
-	return result
+	return strings.TrimSpace(readLineFromConsole(message))
 }
 
-func AttachDefaultRequestHeaders(req *http.Request) {
-	req.Header.Set("Content-Type", globalvars.ApplicationJson)
-	req.Header.Set("User-Agent", globalvars.DefaultUserAgent)
-	req.Header.Set("Accept-Encoding", globalvars.AcceptEncodingHeader)
-	req.Header.Set("Origin", globalvars.Origin)
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9,ru;q=0.8,ru-RU;q=0.7")
-	req.Header.Set("Pragma", "no-cache")
-	req.Header.Set("Sec-Fetch-Dest", "empty")
-	req.Header.Set("Sec-Fetch-Mode", "cors")
-	req.Header.Set("Sec-Fetch-Site", "same-origin")
-	req.Header.Set("Sec-Ch-Ua", `"Not A;Brand";v="8", "Chromium";v="138", "Google Chrome";v="138"`)
-	req.Header.Set("Sec-Ch-Ua-Mobile", "?0")
-	req.Header.Set("Sec-Ch-Ua-Platform", `"Windows"`)
-	req.Header.Set("priority", "u=1, i")
+// readLineFromConsole reads a line from standard input as it was typed,
+// with its line ending.
+func readLineFromConsole(message string) string {
+	fmt.Print(message)
+	line, _ := stdinReader.ReadString('\n')
+	return line
 }
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"bot-main/globalvars"
+	"bot-main/models"
+	"bot-main/vault"
+	"flag"
+	"fmt"
+	"os"
+)
+
+const VaultPassphraseEnv = "INPOL_VAULT_PASSPHRASE"
+
+const vaultUsage = `Usage: bot vault [-vault-file path] <command>
+
+Commands:
+  add <alias>     store login data of an account, asks for email and password
+  list            print aliases and emails of stored accounts
+  remove <alias>  delete the account
+`
+
+// RunVaultCommand handles "vault add/list/remove", args are the ones after "vault".
+func RunVaultCommand(args []string) error {
+	flagSet := flag.NewFlagSet("vault", flag.ContinueOnError)
+	vaultFile := flagSet.String("vault-file", globalvars.DefaultVaultFile, "Encrypted vault file")
+	flagSet.Usage = func() {
+		fmt.Fprint(flagSet.Output(), vaultUsage)
+		flagSet.PrintDefaults()
+	}
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	args = flagSet.Args()
+	if len(args) == 0 {
+		flagSet.Usage()
+		return fmt.Errorf("Vault command is missing")
+	}
+
+	command := args[0]
+	switch {
+	case command == "list" && len(args) == 1:
+	case (command == "add" || command == "remove") && len(args) == 2:
+	default:
+		flagSet.Usage()
+		return fmt.Errorf("Vault command %q is invalid", command)
+	}
+
+	_, statErr := os.Stat(*vaultFile)
+	creating := os.IsNotExist(statErr)
+	if creating && command != "add" {
+		return fmt.Errorf("Vault error: %s doesn't exist", *vaultFile)
+	}
+	passphrase, err := readVaultPassphrase(creating)
+	if err != nil {
+		return err
+	}
+	v, err := vault.Open(*vaultFile, passphrase)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "list":
+		aliases := v.Aliases()
+		if len(aliases) == 0 {
+			fmt.Println("Vault is empty.")
+		}
+		for _, alias := range aliases {
+			loginData, _ := v.Get(alias)
+			fmt.Printf("%s\t%s\n", alias, loginData.Email)
+		}
+		return nil
+	case "add":
+		email := ReadStringFromConsole("Enter email: ")
+		password, err := ReadPasswordFromConsole("Enter password: ")
+		if err != nil {
+			return err
+		}
+		if err = v.Add(args[1], models.LoginData{Email: email, Password: password}); err != nil {
+			return err
+		}
+	case "remove":
+		if err = v.Remove(args[1]); err != nil {
+			return err
+		}
+	}
+	if err = v.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Vault %s saved.\n", *vaultFile)
+	return nil
+}
+
+// readVaultPassphrase takes the passphrase from the environment or asks for it,
+// twice when a new vault is created so a typo doesn't lock it forever.
+func readVaultPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(VaultPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	passphrase, err := ReadPasswordFromConsole("Enter vault passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		repeated, err := ReadPasswordFromConsole("Repeat vault passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if repeated != passphrase {
+			return "", fmt.Errorf("Vault error: passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
+
+// readLoginDataFromVault loads the accounts stored under the aliases,
+// the passphrase is asked for once.
+func readLoginDataFromVault(path string, aliases []string) ([]models.LoginData, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("Vault error: %w", err)
+	}
+	passphrase, err := readVaultPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	v, err := vault.Open(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	loginData := make([]models.LoginData, 0, len(aliases))
+	for _, alias := range aliases {
+		data, err := v.Get(alias)
+		if err != nil {
+			return nil, err
+		}
+		loginData = append(loginData, data)
+	}
+	return loginData, nil
+}
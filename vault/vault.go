@@ -0,0 +1,234 @@
+package vault
+
+import (
+	"bot-main/models"
+	modelerrors "bot-main/models/errors"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	formatVersion = 1
+	kdfArgon2id   = "argon2id"
+	cipherName    = "xchacha20poly1305"
+	saltSize      = 16
+)
+
+// KDFParams are the Argon2id parameters the vault key is derived with.
+// They are kept in the file, so they can be raised without breaking old vaults.
+type KDFParams struct {
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// DefaultKDFParams follow RFC 9106 second recommended option.
+var DefaultKDFParams = KDFParams{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// Most memory in KiB and passes a vault file may ask for, an edited file
+// mustn't make opening it exhaust the machine.
+const (
+	maxKDFMemory = 1024 * 1024
+	maxKDFTime   = 64
+)
+
+// validate checks parameters read from a file, argon2 panics on zero
+// passes or threads and needs at least 8 KiB of memory per thread.
+func (p KDFParams) validate() error {
+	if p.Time < 1 || p.Time > maxKDFTime {
+		return fmt.Errorf("has invalid KDF time %d, expected 1 to %d", p.Time, maxKDFTime)
+	}
+	if p.Threads < 1 {
+		return fmt.Errorf("has invalid KDF threads %d, expected at least 1", p.Threads)
+	}
+	if minMemory := 8 * uint32(p.Threads); p.Memory < minMemory || p.Memory > maxKDFMemory {
+		return fmt.Errorf("has invalid KDF memory %d KiB, expected %d to %d KiB", p.Memory, minMemory, maxKDFMemory)
+	}
+	return nil
+}
+
+// vaultFile is what is stored on disk, only the accounts are encrypted.
+// Header fields are authenticated as additional data.
+type vaultFile struct {
+	Version    int       `json:"version"`
+	KDF        string    `json:"kdf"`
+	KDFParams  KDFParams `json:"kdfParams"`
+	Cipher     string    `json:"cipher"`
+	Salt       []byte    `json:"salt"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+type account struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Vault is a passphrase protected file with login data of several accounts
+// stored under aliases.
+type Vault struct {
+	path       string
+	passphrase []byte
+	params     KDFParams
+	accounts   map[string]account
+}
+
+// Open decrypts the vault at path. A missing file gives an empty vault
+// which is created on the first Save.
+func Open(path, passphrase string) (*Vault, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("Vault error: passphrase is empty")
+	}
+	v := &Vault{
+		path:       path,
+		passphrase: []byte(passphrase),
+		params:     DefaultKDFParams,
+		accounts:   make(map[string]account),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Vault error reading %s: %w", path, err)
+	}
+
+	var file vaultFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("Vault %s JSON parcing error: %w", path, err)
+	}
+	if file.Version != formatVersion || file.KDF != kdfArgon2id || file.Cipher != cipherName {
+		return nil, fmt.Errorf("Vault error: %s has unsupported format %d (%s, %s)", path, file.Version, file.KDF, file.Cipher)
+	}
+	if err = file.KDFParams.validate(); err != nil {
+		return nil, fmt.Errorf("Vault error: %s %w", path, err)
+	}
+	aead, err := chacha20poly1305.NewX(deriveKey(v.passphrase, file.Salt, file.KDFParams))
+	if err != nil {
+		return nil, fmt.Errorf("Vault error creating cipher: %w", err)
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("Vault error: %s has invalid nonce", path)
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, file.additionalData())
+	if err != nil {
+		return nil, modelerrors.InvalidVaultPassphraseError{
+			Message: fmt.Sprintf("❌ Vault %s can't be decrypted, the passphrase is wrong or the file is damaged", path),
+		}
+	}
+	if err = json.Unmarshal(plaintext, &v.accounts); err != nil {
+		return nil, fmt.Errorf("Vault %s accounts JSON parcing error: %w", path, err)
+	}
+	v.params = file.KDFParams
+	return v, nil
+}
+
+// Add stores login data under the alias, replacing what was there.
+func (v *Vault) Add(alias string, loginData models.LoginData) error {
+	alias = strings.TrimSpace(alias)
+	if alias == "" {
+		return fmt.Errorf("Vault error: alias is empty")
+	}
+	if loginData.Email == "" || loginData.Password == "" {
+		return fmt.Errorf("Vault error: email and password are required for %s", alias)
+	}
+	v.accounts[alias] = account{Email: loginData.Email, Password: loginData.Password}
+	return nil
+}
+
+func (v *Vault) Remove(alias string) error {
+	alias = strings.TrimSpace(alias)
+	if _, ok := v.accounts[alias]; !ok {
+		return fmt.Errorf("Vault error: no account with alias %s", alias)
+	}
+	delete(v.accounts, alias)
+	return nil
+}
+
+func (v *Vault) Get(alias string) (models.LoginData, error) {
+	stored, ok := v.accounts[alias]
+	if !ok {
+		return models.LoginData{}, fmt.Errorf("Vault error: no account with alias %s", alias)
+	}
+	return models.LoginData{Email: stored.Email, Password: stored.Password}, nil
+}
+
+// Aliases returns the sorted aliases of stored accounts.
+func (v *Vault) Aliases() []string {
+	aliases := make([]string, 0, len(v.accounts))
+	for alias := range v.accounts {
+		aliases = append(aliases, alias)
+	}
+	slices.Sort(aliases)
+	return aliases
+}
+
+// Save encrypts the accounts with a fresh salt and nonce and replaces
+// the file through a temporary one, readable by the owner only.
+func (v *Vault) Save() error {
+	plaintext, err := json.Marshal(v.accounts)
+	if err != nil {
+		return fmt.Errorf("Vault error encoding JSON: %w", err)
+	}
+	file := vaultFile{
+		Version:   formatVersion,
+		KDF:       kdfArgon2id,
+		KDFParams: v.params,
+		Cipher:    cipherName,
+		Salt:      make([]byte, saltSize),
+		Nonce:     make([]byte, chacha20poly1305.NonceSizeX),
+	}
+	if _, err = rand.Read(file.Salt); err != nil {
+		return fmt.Errorf("Vault error generating salt: %w", err)
+	}
+	if _, err = rand.Read(file.Nonce); err != nil {
+		return fmt.Errorf("Vault error generating nonce: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(deriveKey(v.passphrase, file.Salt, file.KDFParams))
+	if err != nil {
+		return fmt.Errorf("Vault error creating cipher: %w", err)
+	}
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, file.additionalData())
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Vault error encoding JSON: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(v.path), filepath.Base(v.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("Vault error saving %s: %w", v.path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Vault error saving %s: %w", v.path, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("Vault error saving %s: %w", v.path, err)
+	}
+	if err = os.Rename(tmp.Name(), v.path); err != nil {
+		return fmt.Errorf("Vault error saving %s: %w", v.path, err)
+	}
+	return nil
+}
+
+func deriveKey(passphrase, salt []byte, params KDFParams) []byte {
+	return argon2.IDKey(passphrase, salt, params.Time, params.Memory, params.Threads, chacha20poly1305.KeySize)
+}
+
+// additionalData binds the header to the ciphertext, so KDF parameters
+// can't be swapped without the passphrase.
+func (f vaultFile) additionalData() []byte {
+	return fmt.Appendf(nil, "%d|%s|%d|%d|%d|%s|%x", f.Version, f.KDF,
+		f.KDFParams.Time, f.KDFParams.Memory, f.KDFParams.Threads, f.Cipher, f.Salt)
+}
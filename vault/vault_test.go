@@ -0,0 +1,142 @@
+package vault
+
+import (
+	"bot-main/models"
+	modelerrors "bot-main/models/errors"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Cheap parameters keep tests fast, the real ones take a noticeable time by design.
+var testKDFParams = KDFParams{Time: 1, Memory: 1024, Threads: 1}
+
+func newTestVault(t *testing.T, path, passphrase string) *Vault {
+	v, err := Open(path, passphrase)
+	assert.NoError(t, err)
+	v.params = testKDFParams
+	return v
+}
+
+func TestVaultRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "vault.json")
+	v := newTestVault(t, path, "correct horse")
+	assert.NoError(t, v.Add("anna", models.LoginData{Email: "anna@example.com", Password: "anna-secret"}))
+	assert.NoError(t, v.Add("jan", models.LoginData{Email: "jan@example.com", Password: "jan-secret"}))
+	assert.NoError(t, v.Save())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "anna")
+	assert.NotContains(t, string(data), "secret")
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	reopened, err := Open(path, "correct horse")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"anna", "jan"}, reopened.Aliases())
+	loginData, err := reopened.Get("jan")
+	assert.NoError(t, err)
+	assert.Equal(t, models.LoginData{Email: "jan@example.com", Password: "jan-secret"}, loginData)
+
+	assert.NoError(t, reopened.Remove("anna"))
+	assert.Error(t, reopened.Remove("anna"))
+	assert.NoError(t, reopened.Save())
+	reopened, err = Open(path, "correct horse")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"jan"}, reopened.Aliases())
+	_, err = reopened.Get("anna")
+	assert.Error(t, err)
+}
+
+func TestVaultWrongPassphrase(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "vault.json")
+	v := newTestVault(t, path, "correct horse")
+	assert.NoError(t, v.Add("anna", models.LoginData{Email: "anna@example.com", Password: "anna-secret"}))
+	assert.NoError(t, v.Save())
+
+	_, err := Open(path, "battery staple")
+	assert.ErrorAs(t, err, &modelerrors.InvalidVaultPassphraseError{})
+}
+
+func TestVaultTamperedHeader(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "vault.json")
+	v := newTestVault(t, path, "correct horse")
+	assert.NoError(t, v.Add("anna", models.LoginData{Email: "anna@example.com", Password: "anna-secret"}))
+	assert.NoError(t, v.Save())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var file vaultFile
+	assert.NoError(t, json.Unmarshal(data, &file))
+	file.KDFParams.Time++
+	data, err = json.Marshal(file)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+
+	_, err = Open(path, "correct horse")
+	assert.ErrorAs(t, err, &modelerrors.InvalidVaultPassphraseError{})
+}
+
+func TestVaultValidation(t *testing.T) {
+	t.Parallel()
+
+	_, err := Open(filepath.Join(t.TempDir(), "vault.json"), "")
+	assert.Error(t, err)
+
+	v := newTestVault(t, filepath.Join(t.TempDir(), "vault.json"), "correct horse")
+	assert.Error(t, v.Add(" ", models.LoginData{Email: "anna@example.com", Password: "anna-secret"}))
+	assert.Error(t, v.Add("anna", models.LoginData{Email: "anna@example.com"}))
+	assert.Empty(t, v.Aliases())
+
+	assert.NoError(t, v.Add(" anna ", models.LoginData{Email: "anna@example.com", Password: "anna-secret"}))
+	assert.NoError(t, v.Remove("  anna"))
+	assert.Empty(t, v.Aliases())
+}
+
+func TestVaultInvalidKDFParams(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		params KDFParams
+	}{
+		{name: "Zero time", params: KDFParams{Time: 0, Memory: 1024, Threads: 1}},
+		{name: "Too much time", params: KDFParams{Time: 1000, Memory: 1024, Threads: 1}},
+		{name: "Zero threads", params: KDFParams{Time: 1, Memory: 1024, Threads: 0}},
+		{name: "Too little memory", params: KDFParams{Time: 1, Memory: 8, Threads: 4}},
+		{name: "Too much memory", params: KDFParams{Time: 1, Memory: 1 << 30, Threads: 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "vault.json")
+			v := newTestVault(t, path, "correct horse")
+			assert.NoError(t, v.Add("anna", models.LoginData{Email: "anna@example.com", Password: "anna-secret"}))
+			assert.NoError(t, v.Save())
+
+			data, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			var file vaultFile
+			assert.NoError(t, json.Unmarshal(data, &file))
+			file.KDFParams = tc.params
+			data, err = json.Marshal(file)
+			assert.NoError(t, err)
+			assert.NoError(t, os.WriteFile(path, data, 0o600))
+
+			_, err = Open(path, "correct horse")
+			assert.ErrorContains(t, err, "invalid KDF")
+		})
+	}
+}